@@ -1,11 +1,46 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/gkarthikreddi/tcp/pkg/cli"
+	"github.com/gkarthikreddi/tcp/pkg/stack"
 	"github.com/gkarthikreddi/tcp/tools/cmdparser"
 )
 
 func main() {
+	topo := flag.String("topo", "", "topology file to load, defaults to the built-in square topology")
+	script := flag.String("script", "", "script of cli commands to execute at startup")
+	cont := flag.Bool("continue-on-error", false, "keep executing the script when a command fails")
+	seed := flag.Int64("seed", 0, "seed of the link impairment random generator, random when 0")
+	capture := flag.String("capture", "", "pcapng file capturing the frames of every interface")
+	flag.Parse()
+
+	if *seed != 0 {
+		stack.SetImpairmentSeed(*seed)
+	}
+
+	if err := cli.InitTopology(*topo); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	cli.InitNwCli()
+
+	if *capture != "" {
+		if err := stack.StartGlobalCapture(*capture); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer stack.StopGlobalCapture()
+	}
+
+	if *script != "" {
+		if err := cmdparser.ExecuteScript(*script, *cont); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 	cmdparser.CommandParser()
 }
@@ -2,8 +2,9 @@ module github.com/gkarthikreddi/tcp
 
 go 1.23.2
 
+require github.com/jedib0t/go-pretty/v6 v6.6.1
+
 require (
-	github.com/jedib0t/go-pretty/v6 v6.6.1 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
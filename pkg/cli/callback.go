@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
 	"github.com/gkarthikreddi/tcp/pkg/stack"
@@ -22,6 +23,12 @@ func showHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 	case SHOW_TOPO:
 		dumpGraph(graph)
 		return true
+	case TOPO_DOT:
+		dumpGraphDot(graph)
+		return true
+	case TOPO_ASCII:
+		dumpGraphAscii(graph)
+		return true
 	case ARP_TABLE:
 		dumpArpTable(node)
 		return true
@@ -31,6 +38,24 @@ func showHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 	case RT_TABLE:
 		dumpRoutingTable(node)
 		return true
+	case STP_SHOW:
+		dumpSpanningTree(node)
+		return true
+	case CHANNEL_SHOW:
+		dumpEtherchannel(node)
+		return true
+	case STORM_SHOW:
+		dumpStormControl(node)
+		return true
+	case PORTSEC_SHOW:
+		dumpPortSecurity(node)
+		return true
+	case MONITOR_SHOW:
+		dumpMonitorSessions(node)
+		return true
+	case LLDP_SHOW:
+		dumpLldpNeighbors(node)
+		return true
 	}
 	return false
 }
@@ -41,12 +66,21 @@ func arpHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 
 	var node *network.Node
 	var ip *network.Ip
+	var mac [6]byte
+	var intfName string
+	var secs int
 	for curr := buff; curr != nil; curr = curr.Next {
-		if curr.Data.Id == "node-name" {
+		switch curr.Data.Id {
+		case "node-name":
 			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
-		}
-		if curr.Data.Id == "ip-addr" {
+		case "ip-addr":
 			ip = &network.Ip{Addr: tools.ConvertStrToIp(curr.Data.Value)}
+		case "mac-addr":
+			mac = tools.ConvertStrToMac(curr.Data.Value)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "seconds":
+			secs, _ = strconv.Atoi(curr.Data.Value)
 		}
 	}
 
@@ -60,10 +94,257 @@ func arpHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 	case ARPALL_HANDLER:
 		stack.SendArpAll(node)
 		return true
+	case ARP_STATIC:
+		if err := stack.AddStaticArpEntry(node, ip.Addr, mac, intfName); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	case ARP_NO_STATIC:
+		if err := stack.DeleteStaticArpEntry(node, ip.Addr); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	case ARP_CLEAR:
+		if ip != nil {
+			stack.ClearArpTable(node, &ip.Addr)
+		} else {
+			stack.ClearArpTable(node, nil)
+		}
+		return true
+	case ARP_TIMEOUT:
+		stack.SetArpTimeout(time.Duration(secs) * time.Second)
+		return true
+	}
+	return false
+}
+
+func macHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var mac [6]byte
+	var vlan uint16
+	var intfName string
+	var secs int
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "mac-addr":
+			mac = tools.ConvertStrToMac(curr.Data.Value)
+		case "vlan-id":
+			num, _ := strconv.Atoi(curr.Data.Value)
+			vlan = uint16(num)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "seconds":
+			secs, _ = strconv.Atoi(curr.Data.Value)
+		}
+	}
+
+	var err error
+	switch code {
+	case MAC_STATIC:
+		err = stack.AddStaticMacEntry(node, mac, vlan, intfName)
+	case MAC_NO_STATIC:
+		err = stack.DeleteStaticMacEntry(node, mac, vlan)
+	case MAC_CLEAR:
+		stack.ClearMacTable(node)
+	case MAC_TIMEOUT:
+		stack.SetMacTimeout(time.Duration(secs) * time.Second)
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func stpHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var priority int
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "priority":
+			priority, _ = strconv.Atoi(curr.Data.Value)
+		}
+	}
+
+	switch code {
+	case STP_ENABLE:
+		stack.SetStpEnabled(node, true)
+	case STP_DISABLE:
+		stack.SetStpEnabled(node, false)
+	case STP_PRIORITY:
+		if err := stack.SetStpPriority(node, uint16(priority)); err != nil {
+			fmt.Println(err)
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func stormHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var intfName, kind, action string
+	var pps uint64
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "storm-type":
+			kind = curr.Data.Value
+		case "storm-action":
+			action = curr.Data.Value
+		case "pps":
+			pps, _ = strconv.ParseUint(curr.Data.Value, 10, 32)
+		}
+	}
+
+	var err error
+	switch code {
+	case STORM_LEVEL:
+		err = network.NodeSetIntfStormLevel(node, intfName, network.StormType(kind), uint32(pps))
+	case STORM_ACTION:
+		err = network.NodeSetIntfStormAction(node, intfName, network.StormAction(action))
+	case STORM_NO:
+		err = network.NodeUnsetIntfStormLevel(node, intfName, network.StormType(kind))
+	case STORM_CLEAR:
+		stack.ClearStormCounters(node)
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func portSecHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var intfName, violation string
+	var maximum int
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "max-macs":
+			maximum, _ = strconv.Atoi(curr.Data.Value)
+		case "violation-mode":
+			violation = curr.Data.Value
+		}
+	}
+
+	var err error
+	switch code {
+	case PORTSEC_ENABLE:
+		err = network.NodeSetIntfPortSecurity(node, intfName)
+	case PORTSEC_MAX:
+		err = network.NodeSetIntfPortSecMaximum(node, intfName, maximum)
+	case PORTSEC_VIOL:
+		err = network.NodeSetIntfPortSecViolation(node, intfName, network.PortSecViolation(violation))
+	case PORTSEC_STICKY:
+		err = stack.SetIntfPortSecSticky(node, intfName, true)
+	case PORTSEC_NOSTKY:
+		err = stack.SetIntfPortSecSticky(node, intfName, false)
+	case PORTSEC_NO:
+		err = stack.UnsetIntfPortSecurity(node, intfName)
+	case PORTSEC_CLEAR:
+		stack.ClearPortSecCounters(node)
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func lldpHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	if buff != nil && buff.Data.Id == "node-name" {
+		node, _ = network.GetNodeByNodeName(graph, buff.Data.Value)
+	}
+	switch code {
+	case LLDP_CLEAR:
+		stack.ClearLldpNeighbors(node)
+		return true
 	}
 	return false
 }
 
+func monitorHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var id, vlan int
+	var source, destination string
+	dir := network.MONITOR_BOTH
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "session-id":
+			id, _ = strconv.Atoi(curr.Data.Value)
+		case "source-intf":
+			source = curr.Data.Value
+		case "direction":
+			dir = network.MonitorDir(curr.Data.Value)
+		case "dest-intf":
+			destination = curr.Data.Value
+		case "vlan-id":
+			vlan, _ = strconv.Atoi(curr.Data.Value)
+		}
+	}
+
+	var err error
+	switch code {
+	case MONITOR_ADD:
+		err = network.NodeSetMonitorSession(node, uint16(id), source, dir, destination, uint16(vlan))
+	case MONITOR_NO:
+		err = network.NodeUnsetMonitorSession(node, uint16(id))
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
 func l3ConfigHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 	code := cmdparser.ExtractCmdCode(buff)
 	buff = buff.Next
@@ -115,16 +396,278 @@ func pingHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
 	case PING_HANDLER:
 		var node *network.Node
 		var dstIp [4]byte
+		count, size, timeout := stack.PING_DEF_COUNT, stack.PING_DEF_SIZE, stack.PING_DEF_TIMEOUT
+		df := false
 
 		for curr := buff; curr != nil; curr = curr.Next {
-			if curr.Data.Id == "node-name" {
+			switch curr.Data.Id {
+			case "node-name":
 				node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
-			} else if curr.Data.Id == "ip-addr" {
+			case "ip-addr":
 				dstIp = tools.ConvertStrToIp(curr.Data.Value)
+			case "count":
+				count, _ = strconv.Atoi(curr.Data.Value)
+			case "size":
+				size, _ = strconv.Atoi(curr.Data.Value)
+			case "timeout":
+				secs, _ := strconv.Atoi(curr.Data.Value)
+				timeout = time.Duration(secs) * time.Second
+			case "df-bit":
+				df = curr.Data.Value == "on"
 			}
 		}
 
-        stack.Ping(node, dstIp)
+		if err := stack.Ping(node, dstIp, count, size, timeout, df); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	case TRACE_HANDLER:
+		var node *network.Node
+		var dstIp [4]byte
+		maxHops := stack.TRACE_DEF_HOPS
+
+		for curr := buff; curr != nil; curr = curr.Next {
+			switch curr.Data.Id {
+			case "node-name":
+				node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+			case "ip-addr":
+				dstIp = tools.ConvertStrToIp(curr.Data.Value)
+			case "max-hops":
+				maxHops, _ = strconv.Atoi(curr.Data.Value)
+			}
+		}
+
+		if err := stack.Traceroute(node, dstIp, maxHops, stack.TRACE_DEF_TIMEOUT); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
 	}
 	return false
 }
+
+func sourceHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+	switch code {
+	case SOURCE_HANDLER, SOURCE_CONT:
+		var file string
+		for curr := buff; curr != nil; curr = curr.Next {
+			if curr.Data.Id == "file" {
+				file = curr.Data.Value
+			}
+		}
+
+		if err := cmdparser.ExecuteScript(file, code == SOURCE_CONT); err != nil {
+			fmt.Println(err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func topoConfigHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node, peerNode *network.Node
+	var newNode, intfName, peerIntf, ipAddr, l2Mode, channelMode string
+	var mask uint8
+	var vlan, channelId uint16
+	var mac [6]byte
+	var mtu int
+	var cost uint = 1
+
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "new-node":
+			newNode = curr.Data.Value
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "peer-node":
+			peerNode, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "peer-intf":
+			peerIntf = curr.Data.Value
+		case "cost":
+			num, _ := strconv.Atoi(curr.Data.Value)
+			cost = uint(num)
+		case "ip-addr":
+			ipAddr = curr.Data.Value
+		case "mask":
+			num, _ := strconv.Atoi(curr.Data.Value)
+			mask = uint8(num)
+		case "l2-mode":
+			l2Mode = curr.Data.Value
+		case "vlan-id":
+			num, _ := strconv.Atoi(curr.Data.Value)
+			vlan = uint16(num)
+		case "mac-addr":
+			mac = tools.ConvertStrToMac(curr.Data.Value)
+		case "channel-id":
+			num, _ := strconv.Atoi(curr.Data.Value)
+			channelId = uint16(num)
+		case "channel-mode":
+			channelMode = curr.Data.Value
+		case "mtu":
+			mtu, _ = strconv.Atoi(curr.Data.Value)
+		}
+	}
+
+	var err error
+	switch code {
+	case NODE_ADD:
+		_, err = stack.AddNode(graph, newNode, ipAddr)
+	case NODE_DEL:
+		err = stack.RemoveNode(graph, node)
+	case LINK_ADD:
+		err = stack.AddLink(node, peerNode, intfName, peerIntf, cost)
+	case LINK_DEL:
+		err = stack.RemoveLink(node, intfName)
+	case INTF_DEL:
+		err = stack.RemoveInterface(node, intfName)
+	case INTF_IP:
+		err = stack.SetIntfIpAddr(node, intfName, ipAddr, mask)
+	case INTF_MAC:
+		err = stack.SetIntfMacAddr(node, intfName, mac)
+	case INTF_L2MODE:
+		err = stack.SetIntfL2Mode(node, intfName, network.L2Mode(l2Mode))
+	case INTF_VLAN:
+		err = network.NodeSetIntfVlanMembership(node, intfName, vlan)
+	case INTF_NATIVE:
+		err = network.NodeSetIntfNativeVlan(node, intfName, vlan)
+	case INTF_NO_NATIVE:
+		err = network.NodeUnsetIntfNativeVlan(node, intfName)
+	case INTF_SHUT:
+		err = network.NodeSetIntfShutdown(node, intfName, true)
+	case INTF_NO_SHUT:
+		err = network.NodeSetIntfShutdown(node, intfName, false)
+	case INTF_PROXY_ARP:
+		err = network.NodeSetIntfProxyArp(node, intfName, true)
+	case INTF_NO_PROXY:
+		err = network.NodeSetIntfProxyArp(node, intfName, false)
+	case INTF_MTU:
+		err = network.NodeSetIntfMtu(node, intfName, mtu)
+	case INTF_NO_MTU:
+		err = network.NodeSetIntfMtu(node, intfName, network.INTF_DEF_MTU)
+	case INTF_CHANNEL:
+		err = stack.SetIntfChannelGroup(node, intfName, channelId, network.ChannelMode(channelMode))
+	case INTF_NO_CHAN:
+		err = stack.UnsetIntfChannelGroup(node, intfName)
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func linkConfigHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var intfName, value, dist string
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "value":
+			value = curr.Data.Value
+		case "jitter-dist":
+			dist = curr.Data.Value
+		}
+	}
+
+	intf, err := network.GetIntfByIntfName(node, intfName)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	var impair network.Impairment
+	if curr := network.GetLinkImpairment(intf); curr != nil {
+		impair = *curr
+	}
+
+	num, _ := strconv.ParseFloat(value, 64)
+	ms := time.Duration(num * float64(time.Millisecond))
+	switch code {
+	case LINK_DELAY:
+		impair.Delay = ms
+	case LINK_JITTER:
+		impair.Jitter = ms
+		if dist != "" {
+			impair.JitterDist = network.JitterDist(dist)
+		}
+	case LINK_LOSS:
+		impair.Loss = num
+	case LINK_DUP:
+		impair.Duplicate = num
+	case LINK_REORDER:
+		impair.Reorder = num
+	case LINK_BW:
+		impair.Bandwidth = uint64(num) * 1000
+	case LINK_CLEAR:
+		impair = network.Impairment{}
+	default:
+		return false
+	}
+
+	if err := network.SetLinkImpairment(node, intfName, impair); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func captureHandler(param *cmdparser.Param, buff *cmdparser.SerBuff) bool {
+	code := cmdparser.ExtractCmdCode(buff)
+	buff = buff.Next
+
+	var node *network.Node
+	var intfName, file string
+	for curr := buff; curr != nil; curr = curr.Next {
+		switch curr.Data.Id {
+		case "node-name":
+			node, _ = network.GetNodeByNodeName(graph, curr.Data.Value)
+		case "intf-name":
+			intfName = curr.Data.Value
+		case "file":
+			file = curr.Data.Value
+		}
+	}
+
+	var err error
+	switch code {
+	case CAPTURE_START, CAPTURE_STOP:
+		var intf *network.Interface
+		if intf, err = network.GetIntfByIntfName(node, intfName); err != nil {
+			break
+		}
+		if code == CAPTURE_START {
+			err = stack.StartIntfCapture(intf, file)
+		} else {
+			err = stack.StopIntfCapture(intf)
+		}
+	case CAPTURE_ALL:
+		err = stack.StartGlobalCapture(file)
+	case CAPTURE_NONE:
+		err = stack.StopGlobalCapture()
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
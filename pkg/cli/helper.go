@@ -2,8 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
 	"github.com/gkarthikreddi/tcp/pkg/stack"
@@ -21,148 +24,6 @@ const (
 	Cyan   = "\033[36m"
 )
 
-func buildGraph() *network.Graph {
-	graph := network.CreateNewGraph("Topology")
-	node1 := network.CreateGraphNode(graph, "r0")
-	node2 := network.CreateGraphNode(graph, "r1")
-	node3 := network.CreateGraphNode(graph, "r2")
-
-	network.InsertLinkBetweenNodes(node1, node2, "eth00", "eth01", 1)
-	network.InsertLinkBetweenNodes(node2, node3, "eth02", "eth03", 1)
-	network.InsertLinkBetweenNodes(node3, node1, "eth05", "eth04", 1)
-
-	network.NodeSetLbAddr(node1, "122.1.1.0")
-	network.NodeSetIntfIpAddr(node1, "eth04", "40.1.1.1", 24)
-	network.NodeSetIntfIpAddr(node1, "eth00", "20.1.1.1", 24)
-
-	network.NodeSetIntfIpAddr(node2, "eth02", "30.1.1.1", 24)
-	network.NodeSetIntfIpAddr(node2, "eth01", "20.1.1.2", 24)
-
-	network.NodeSetIntfIpAddr(node3, "eth03", "30.1.1.2", 24)
-	network.NodeSetIntfIpAddr(node3, "eth05", "40.1.1.2", 24)
-
-	stack.InitNetworkListening(graph)
-	stack.InitRoutingTable(graph)
-	return graph
-}
-
-func buildL2SwitchGraph() *network.Graph {
-	graph := network.CreateNewGraph("Simpel L2 switch demo graph")
-	h1 := network.CreateGraphNode(graph, "H1")
-	h2 := network.CreateGraphNode(graph, "H2")
-	h3 := network.CreateGraphNode(graph, "H3")
-	h4 := network.CreateGraphNode(graph, "H4")
-	l2sw := network.CreateGraphNode(graph, "L2SW")
-
-	network.InsertLinkBetweenNodes(h1, l2sw, "eth0/5", "eth0/4", 1)
-	network.InsertLinkBetweenNodes(h2, l2sw, "eth0/8", "eth0/3", 1)
-	network.InsertLinkBetweenNodes(h3, l2sw, "eth0/6", "eth0/2", 1)
-	network.InsertLinkBetweenNodes(h4, l2sw, "eth0/7", "eth0/1", 1)
-
-	network.NodeSetLbAddr(h1, "122.1.1.1")
-	network.NodeSetIntfIpAddr(h1, "eth0/5", "10.1.1.2", 24)
-
-	network.NodeSetLbAddr(h2, "122.1.1.2")
-	network.NodeSetIntfIpAddr(h2, "eth0/8", "10.1.1.4", 24)
-
-	network.NodeSetLbAddr(h3, "122.1.1.3")
-	network.NodeSetIntfIpAddr(h3, "eth0/6", "10.1.1.1", 24)
-
-	network.NodeSetLbAddr(h4, "122.1.1.4")
-	network.NodeSetIntfIpAddr(h4, "eth0/5", "10.1.1.3", 24)
-
-	network.NodeSetIntfL2Mode(l2sw, "eth0/1", network.ACCESS)
-	network.NodeSetIntfL2Mode(l2sw, "eth0/2", network.ACCESS)
-	network.NodeSetIntfL2Mode(l2sw, "eth0/3", network.ACCESS)
-	network.NodeSetIntfL2Mode(l2sw, "eth0/4", network.ACCESS)
-
-	stack.InitNetworkListening(graph)
-	stack.InitRoutingTable(graph)
-	return graph
-}
-
-func buildDualSwitchGraph() *network.Graph {
-	graph := network.CreateNewGraph("Dual Switch Topo")
-	H1 := network.CreateGraphNode(graph, "H1")
-	network.NodeSetLbAddr(H1, "122.1.1.1")
-	H2 := network.CreateGraphNode(graph, "H2")
-	network.NodeSetLbAddr(H2, "122.1.1.2")
-	H3 := network.CreateGraphNode(graph, "H3")
-	network.NodeSetLbAddr(H3, "122.1.1.3")
-	H4 := network.CreateGraphNode(graph, "H4")
-	network.NodeSetLbAddr(H4, "122.1.1.4")
-	H5 := network.CreateGraphNode(graph, "H5")
-	network.NodeSetLbAddr(H5, "122.1.1.5")
-	H6 := network.CreateGraphNode(graph, "H6")
-	network.NodeSetLbAddr(H6, "122.1.1.6")
-
-	L2SW1 := network.CreateGraphNode(graph, "L2SW1")
-	L2SW2 := network.CreateGraphNode(graph, "L2SW2")
-
-	network.InsertLinkBetweenNodes(H1, L2SW1, "eth0/1", "eth0/2", 1)
-	network.InsertLinkBetweenNodes(H2, L2SW1, "eth0/3", "eth0/7", 1)
-	network.InsertLinkBetweenNodes(H3, L2SW1, "eth0/4", "eth0/6", 1)
-	network.InsertLinkBetweenNodes(L2SW1, L2SW2, "eth0/5", "eth0/7", 1)
-	network.InsertLinkBetweenNodes(H5, L2SW2, "eth0/8", "eth0/9", 1)
-	network.InsertLinkBetweenNodes(H4, L2SW2, "eth0/11", "eth0/12", 1)
-	network.InsertLinkBetweenNodes(H6, L2SW2, "eth0/11", "eth0/10", 1)
-
-	network.NodeSetIntfIpAddr(H1, "eth0/1", "10.1.1.1", 24)
-	network.NodeSetIntfIpAddr(H2, "eth0/3", "10.1.1.2", 24)
-	network.NodeSetIntfIpAddr(H3, "eth0/4", "10.1.1.3", 24)
-	network.NodeSetIntfIpAddr(H4, "eth0/11", "10.1.1.4", 24)
-	network.NodeSetIntfIpAddr(H5, "eth0/8", "10.1.1.5", 24)
-	network.NodeSetIntfIpAddr(H6, "eth0/11", "10.1.1.6", 24)
-
-	network.NodeSetIntfL2Mode(L2SW1, "eth0/2", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW1, "eth0/2", 10)
-	network.NodeSetIntfL2Mode(L2SW1, "eth0/7", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW1, "eth0/7", 10)
-	network.NodeSetIntfL2Mode(L2SW1, "eth0/5", network.TRUNK)
-	network.NodeSetIntfVlanMembership(L2SW1, "eth0/5", 10)
-	network.NodeSetIntfVlanMembership(L2SW1, "eth0/5", 11)
-	network.NodeSetIntfL2Mode(L2SW1, "eth0/6", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW1, "eth0/6", 11)
-
-	network.NodeSetIntfL2Mode(L2SW2, "eth0/7", network.TRUNK)
-	network.NodeSetIntfVlanMembership(L2SW2, "eth0/7", 10)
-	network.NodeSetIntfVlanMembership(L2SW2, "eth0/7", 11)
-	network.NodeSetIntfL2Mode(L2SW2, "eth0/9", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW2, "eth0/9", 10)
-	network.NodeSetIntfL2Mode(L2SW2, "eth0/10", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW2, "eth0/10", 10)
-	network.NodeSetIntfL2Mode(L2SW2, "eth0/12", network.ACCESS)
-	network.NodeSetIntfVlanMembership(L2SW2, "eth0/12", 11)
-
-	stack.InitNetworkListening(graph)
-	stack.InitRoutingTable(graph)
-
-	return graph
-}
-func buildLinear3NodeTopo() *network.Graph {
-	graph := network.CreateNewGraph("3 node linear topo")
-	R1 := network.CreateGraphNode(graph, "R1")
-	R2 := network.CreateGraphNode(graph, "R2")
-	R3 := network.CreateGraphNode(graph, "R3")
-
-	network.InsertLinkBetweenNodes(R1, R2, "eth0/1", "eth0/2", 1)
-	network.InsertLinkBetweenNodes(R2, R3, "eth0/3", "eth0/4", 1)
-
-	network.NodeSetLbAddr(R1, "122.1.1.1")
-	network.NodeSetLbAddr(R2, "122.1.1.2")
-	network.NodeSetLbAddr(R3, "122.1.1.3")
-
-	network.NodeSetIntfIpAddr(R1, "eth0/1", "10.1.1.1", 24)
-	network.NodeSetIntfIpAddr(R2, "eth0/2", "10.1.1.2", 24)
-	network.NodeSetIntfIpAddr(R2, "eth0/3", "11.1.1.2", 24)
-	network.NodeSetIntfIpAddr(R3, "eth0/4", "11.1.1.1", 24)
-
-	stack.InitNetworkListening(graph)
-	stack.InitRoutingTable(graph)
-
-	return graph
-}
-
 func buildSquareTopo() *network.Graph {
 	topo := network.CreateNewGraph("Square Topo")
 	R1 := network.CreateGraphNode(topo, "R1")
@@ -191,125 +52,39 @@ func buildSquareTopo() *network.Graph {
 	network.NodeSetIntfIpAddr(R4, "eth0/5", "30.1.1.2", 24)
 	network.NodeSetIntfIpAddr(R4, "eth0/6", "40.1.1.1", 24)
 
-	stack.InitNetworkListening(topo)
-	stack.InitRoutingTable(topo)
-
 	return topo
 }
 
-var graph = buildSquareTopo()
+var graph *network.Graph
+
+// InitTopology loads the topology file at the given path, or the square topology
+// when no path is given, and starts the udp listeners of all its nodes.
+func InitTopology(path string) error {
+	if path == "" {
+		graph = buildSquareTopo()
+	} else {
+		topo, err := network.LoadTopology(path)
+		if err != nil {
+			return err
+		}
+		graph = topo
+	}
+
+	stack.InitNetworkListening(graph)
+	stack.InitRoutingTable(graph)
+	stack.InitLinkStateHandling()
+	stack.InitArpAging(graph)
+	stack.InitMacAging(graph)
+	stack.InitSpanningTree(graph)
+	stack.InitLinkAggregation(graph)
+	stack.InitPortSecurity(graph)
+	stack.InitLldp(graph)
+	stack.InitIpReassembly()
+	return nil
+}
 
 func dumpGraph(graph *network.Graph) {
 	fmt.Println("Name: " + Cyan + graph.Name + Reset)
-	if graph.Name == "Topology" {
-		fmt.Println(`
-	                 +----------+
-	             0/4 |          |0/0
-	+----------------+   R0     +---------------------------+
-	|     40.1.1.1/24| 122.1.1.0|20.1.1.1/24                |
-	|                +----------+                           |
-	|                                                       |
-	|                                                       |
-	|                                                       |
-	|40.1.1.2/24                                            |20.1.1.2/24
-	|0/5                                                    |0/1
-
-+---+---+                                              +----+-----+
-|       |0/3                                        0/2|          |
-| R2    +----------------------------------------------+    R1    |
-|       |30.1.1.2/24                        30.1.1.1/24|          |
-+-------+                                              +----------+
-        `)
-	} else if graph.Name == "Dual Switch Topo" {
-		fmt.Println(`
-	                                    +---------+                               +----------+
-	                                    |         |                               |          |
-	                                    |  H2     |                               |  H5      |
-	                                    |122.1.1.2|                               |122.1.1.5 |
-	                                    +---+-----+                               +-----+----+
-	                                        |10.1.1.2/24                                +10.1.1.5/24
-	                                        |eth0/3                                     |eth0/8
-	                                        |                                           |
-	                                        |eth0/7,AC,V10                              |eth0/9,AC,V10
-	                                  +-----+----+                                +-----+---+
-	                                  |          |                                |         |
-	   +------+---+                   |          |                                |         |                         +--------+
-	   |  H1      |10.1.1.1/24        |   L2SW1  |eth0/5                    eth0/7| L2SW2   |eth0/10           eth0/11|  H6    |
-	   |122.1.1.1 +-------------------|          |+-------------------------------|         +-------------+----------+122.1.1.6|
-	   +------+---+ eth0/1      eth0/2|          |TR,V10,V11            TR,V10,V11|         |AC,V10        10.1.1.6/24|        |
-	                            AC,V10|          |                                |         |                         +-+------+
-	                                  +-----+----+                                +----+----+
-	                                        |eth0/6                                    |eth0/12
-	                                        |AC,V11                                    |AC,V11
-	                                        |                                          |
-	                                        |                                          |
-	                                        |                                          |
-	                                        |                                          |eth0/11
-	                                        |eth0/4                                    |10.1.1.4/24
-	                                        |10.1.1.3/24                             +--+-----+
-	                                   +----+---+|                                   | H4     |
-	                                   |  H3     |                                   |        |
-	                                   |122.1.1.3|                                   |122.1.1.4|
-	                                   +--------+|                                   +--------+ `)
-	} else if graph.Name == "" {
-		fmt.Println(`
-	                                   +-----------+
-	                                   |  H4       |
-	                                   | 122.1.1.4 |
-	                                   +----+------+
-	                                        |eth0/7 - 10.1.1.3/24
-	                                        |
-	                                        |eth0/1
-	                                   +----+----+                        +---------+
-	   +---------+                     |         |                        |         |
-	   |         |10.1.1.2/24          |   L2Sw  |eth0/2       10.1.1.1/24|  H3     |
-	   |  H1     +---------------------+         +------------------------+122.1.1.3|
-	   |122.1.1.1|eth0/5         eth0/4|         |                 eth0/6 |         |
-	   + --------+                     |         |                        |         |
-	                                   +----+----+                        +---------+
-	                                        |eth0/3
-	                                        |
-	                                        |
-	                                        |
-	                                        |10.1.1.4/24
-	                                        |eth0/8
-	                                  +----++------+
-	                                  |            |
-	                                  |   H2       |
-	                                  |122.1.1.2   |
-	                                  |            |
-	                                  +------------+ `)
-	} else if graph.Name == "3 node linear topo" {
-		fmt.Println(`
-                                        +---------+                                  +----------+
-+--------+                              |         |                                  |R3        |
-|R1      |eth0/1                  eth0/2|R2       |eth0/3                      eth0/4|122.1.1.3 |
-|122.1.1.1+-----------------------------+122.1.1.2|+----------------------------------+         |
-|        |10.1.1.1/24        10.1.1.2/24|         |11.1.1.2/24            11.1.1.1/24|          |
-+--------+                              +-------+-|                                  +----------+ `)
-	} else if graph.Name == "Square Topo" {
-		fmt.Println(`
-  +-----------+                      +--------+                            +--------+
-  |           |eth0/0     10.1.1.2/24|        | eth0/2               eth0/3|        |
-  | R1        +----------------------|  R2    +----------------------------+   R3   |
-  |122.1.1.1  |10.1.1.1/24     eth0/1|122.1.1.2|  20.1.1.1/24   20.1.1.2/24| 122.1.1.3|
-  +---+--+----+                      |        |                            +-       +
-         |eth0/7                     +--------+                            +----+---+
-         | 40.1.1.2/24                                                          | eth0/4   
-         |                                                                      |30.1.1.1/24
-         |                                                                      |
-         |                                                                      |
-         |                                                                      |
-         |                                                                      |
-         |                                                                      |
-         |                          +-----------+                               |
-         |                          |           |                               |
-         |                  eth0/6  |  R4       |                               |
-         +--------------------------+ 122.1.1.4 |                               |
-                         40.1.1.1/24|           +-------------------------------+
-                                    |           |eth0/5
-                                    +-----------+30.1.1.2/24 `)
-	}
 	for curr := graph.List; curr != nil; curr = curr.Next {
 		dumpNode(curr)
 	}
@@ -317,18 +92,27 @@ func dumpGraph(graph *network.Graph) {
 
 func dumpNode(node *network.Node) {
 	fmt.Println("Node name: " + node.Name + "\nLb addr: " + Yellow + tools.ConvertAddrToStr(network.GetNodeIp(node).Addr[:]) + Reset + ", UDP Port: " + strconv.Itoa(network.GetNodePort(node)))
-	for i := 0; node.Intf[i] != nil; i++ {
+	for i := 0; i < network.MAX_INTF_PER_NODE && node.Intf[i] != nil; i++ {
 		dumpInterface(node.Intf[i])
 	}
 }
 
 func dumpInterface(intf *network.Interface) {
-	fmt.Println("\tInterface name: " + Cyan + intf.Name + Reset)
-	nbrNode, _ := network.GetNbrNode(intf)
-	fmt.Println("\t\tLocalNode: " + Cyan + intf.Att_node.Name + Reset + ", Nbr Node: " + Cyan + nbrNode.Name + Reset)
-
-	if network.IsIntfIp(intf) {
-		fmt.Println("\t\tIp addr: " + Yellow + tools.ConvertAddrToStr(network.GetIntfIp(intf).Addr[:]) + Reset + " Mac addr: " + Yellow + tools.ConvertAddrToStr(network.GetIntfMac(intf).Addr[:]) + Reset)
+	fmt.Println("\tInterface name: " + Cyan + intf.Name + Reset + ", State: " + network.IntfStateStr(intf) + ", MTU: " + strconv.Itoa(network.GetIntfMtu(intf)))
+	nbrName := "NA"
+	if nbrNode, err := network.GetNbrNode(intf); err == nil {
+		nbrName = nbrNode.Name
+	}
+	fmt.Println("\t\tLocalNode: " + Cyan + intf.Att_node.Name + Reset + ", Nbr Node: " + Cyan + nbrName + Reset)
+
+	if channel := network.GetIntfChannel(intf); channel != nil {
+		fmt.Printf("\t\tMember of: %s%s%s, Mode: %s, Bundled: %v\n", Cyan, channel.Name, Reset, network.GetIntfChannelMode(intf), network.IsIntfBundled(intf))
+	} else if network.IsIntfIp(intf) {
+		proxyArp := ""
+		if network.IsIntfProxyArp(intf) {
+			proxyArp = ", Proxy arp"
+		}
+		fmt.Println("\t\tIp addr: " + Yellow + tools.ConvertAddrToStr(network.GetIntfIp(intf).Addr[:]) + Reset + " Mac addr: " + Yellow + tools.ConvertAddrToStr(network.GetIntfMac(intf).Addr[:]) + Reset + proxyArp)
 	} else {
 		fmt.Printf("\t\tL2 Mode: %v\t Vlan Membership: ", network.GetIntfL2Mode(intf))
 		for _, val := range network.GetIntfVlanMembership(intf) {
@@ -338,6 +122,9 @@ func dumpInterface(intf *network.Interface) {
 				fmt.Printf("%d ", val)
 			}
 		}
+		if native := network.GetIntfNativeVlan(intf); native != 0 && network.GetIntfL2Mode(intf) == network.TRUNK {
+			fmt.Printf("\t Native Vlan: %d", native)
+		}
 		fmt.Println()
 	}
 }
@@ -345,12 +132,23 @@ func dumpInterface(intf *network.Interface) {
 func dumpArpTable(node *network.Node) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"IP", "MAC", "Interface"})
-	for curr := network.GetNodeArpTable(node); curr != nil; curr = curr.Next {
+	t.AppendHeader(table.Row{"IP", "MAC", "Interface", "Type", "Age", "State"})
+	for _, curr := range stack.GetArpTable(node) {
+		mac := "NA"
+		if curr.State == network.ARP_COMPLETE {
+			mac = tools.ConvertAddrToStr(curr.MacAddr.Addr[:])
+		}
+		kind, age := "dynamic", strconv.Itoa(int(time.Since(curr.Updated).Seconds()))
+		if curr.IsStatic {
+			kind, age = "static", "-"
+		}
 		t.AppendRow(table.Row{
 			tools.ConvertAddrToStr(curr.IpAddr.Addr[:]),
-			tools.ConvertAddrToStr(curr.MacAddr.Addr[:]),
-			curr.Name})
+			mac,
+			curr.Name,
+			kind,
+			age,
+			curr.State})
 	}
 	t.Render()
 }
@@ -358,11 +156,222 @@ func dumpArpTable(node *network.Node) {
 func dumpMacTable(node *network.Node) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"MAC", "Interface"})
-	for curr := network.GetNodeMacTable(node); curr != nil; curr = curr.Next {
+	t.AppendHeader(table.Row{"VLAN", "MAC", "Interface", "Type", "Age"})
+	for _, curr := range stack.GetMacTable(node) {
+		vlan := "NA"
+		if curr.Vlan != 0 {
+			vlan = strconv.Itoa(int(curr.Vlan))
+		}
+		kind, age := "dynamic", strconv.Itoa(int(time.Since(curr.Updated).Seconds()))
+		if curr.IsStatic {
+			kind, age = "static", "-"
+			if intf, err := network.GetIntfByIntfName(node, curr.Name); err == nil && network.IsIntfStickyMac(intf, curr.Vlan, curr.MacAddr.Addr) {
+				kind = "sticky"
+			}
+		}
 		t.AppendRow(table.Row{
+			vlan,
 			tools.ConvertAddrToStr(curr.MacAddr.Addr[:]),
-			curr.Name})
+			curr.Name,
+			kind,
+			age})
+	}
+	t.Render()
+}
+
+func dumpSpanningTree(node *network.Node) {
+	bridge := stack.GetStpBridge(node)
+	if bridge == nil {
+		fmt.Println("Node: " + node.Name + " has no L2 ports")
+		return
+	}
+	if !bridge.Enabled {
+		fmt.Println("Spanning tree is disabled on node: " + node.Name)
+		return
+	}
+
+	own := stack.StpBridgeId(stack.BridgeId(bridge))
+	fmt.Println("Bridge ID: " + Yellow + own + Reset)
+	if bridge.RootPort == "" {
+		fmt.Println("Root ID: " + Yellow + own + Reset + " (this bridge is the root)")
+	} else {
+		fmt.Printf("Root ID: %s%s%s, Root cost: %d, Root port: %s%s%s\n", Yellow, stack.StpBridgeId(bridge.RootId), Reset,
+			bridge.RootCost, Cyan, bridge.RootPort, Reset)
+	}
+	fmt.Printf("Hello time: %v, Max age: %v, Forward delay: %v\n", stack.STP_HELLO_TIME, stack.STP_MAX_AGE, stack.STP_FWD_DELAY)
+
+	names := make([]string, 0, len(bridge.Ports))
+	for name := range bridge.Ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Interface", "Role", "State", "Cost", "Port ID", "Designated Bridge", "Edge"})
+	for _, name := range names {
+		port := bridge.Ports[name]
+		designated := "-"
+		if port.Role == network.STP_DESIGNATED {
+			designated = own
+		} else if port.Heard != nil {
+			designated = stack.StpBridgeId(port.Heard.BridgeId)
+		}
+		t.AppendRow(table.Row{
+			name,
+			port.Role,
+			port.State,
+			port.Cost,
+			fmt.Sprintf("%d.%d", port.Id>>8, port.Id&0xff),
+			designated,
+			port.IsEdge})
+	}
+	t.Render()
+}
+
+func dumpEtherchannel(node *network.Node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Port-channel", "State", "Member", "Mode", "Bundled", "Partner"})
+	for _, channel := range node.Intf {
+		if channel == nil {
+			break
+		}
+		if !network.IsIntfChannel(channel) {
+			continue
+		}
+		state := "down"
+		if network.IsIntfUp(channel) {
+			state = "up"
+		}
+		members := network.GetChannelMembers(channel)
+		if len(members) == 0 {
+			t.AppendRow(table.Row{channel.Name, state, "-", "-", "-", "-"})
+		}
+		for _, member := range members {
+			partner, ok := stack.GetLacpPartner(member)
+			if !ok {
+				partner = "-"
+			}
+			t.AppendRow(table.Row{
+				channel.Name,
+				state,
+				member.Name,
+				network.GetIntfChannelMode(member),
+				network.IsIntfBundled(member),
+				partner})
+		}
+	}
+	t.Render()
+}
+
+func dumpStormControl(node *network.Node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Interface", "Type", "Level (pps)", "Rate (pps)", "Drops", "Action", "State"})
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		storm := network.GetIntfStormControl(intf)
+		if storm == nil {
+			continue
+		}
+		counters := stack.GetStormCounters(intf)
+		for _, kind := range network.StormTypes {
+			level, ok := storm.Levels[kind]
+			if !ok {
+				continue
+			}
+			t.AppendRow(table.Row{
+				intf.Name,
+				kind,
+				level,
+				counters.Rate[kind],
+				counters.Drops[kind],
+				storm.Action,
+				network.IntfStateStr(intf)})
+		}
+	}
+	t.Render()
+}
+
+func dumpPortSecurity(node *network.Node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Interface", "Maximum", "Current", "Sticky", "Violation", "Violations", "Last Violation", "State"})
+	sticky := table.NewWriter()
+	sticky.SetOutputMirror(os.Stdout)
+	sticky.AppendHeader(table.Row{"Interface", "VLAN", "Sticky MAC"})
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		sec := network.GetIntfPortSecurity(intf)
+		if sec == nil {
+			continue
+		}
+		counters := stack.GetPortSecCounters(node, intf)
+		last := "-"
+		if !counters.LastAt.IsZero() {
+			last = fmt.Sprintf("%s vlan %d, %ds ago", tools.ConvertAddrToStr(counters.LastMac[:]), counters.LastVlan, int(time.Since(counters.LastAt).Seconds()))
+		}
+		t.AppendRow(table.Row{
+			intf.Name,
+			sec.Maximum,
+			counters.Current,
+			sec.Sticky,
+			sec.Violation,
+			counters.Violations,
+			last,
+			network.IntfStateStr(intf)})
+		for _, val := range sec.StickyMacs {
+			// written the way the sticky_macs of the topology are, so they can be saved back
+			vlan := "NA"
+			if val.Vlan != 0 {
+				vlan = strconv.Itoa(int(val.Vlan))
+			}
+			sticky.AppendRow(table.Row{intf.Name, vlan, net.HardwareAddr(val.Mac.Addr[:]).String()})
+		}
+	}
+	t.Render()
+	if sticky.Length() > 0 {
+		sticky.Render()
+	}
+}
+
+func dumpLldpNeighbors(node *network.Node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Local Intf", "System Name", "Chassis Id", "Port Id", "Mgmt Addr", "Hold Time"})
+	for _, nbr := range stack.GetLldpNeighbors(node) {
+		addr := "-"
+		if nbr.MgmtAddr != nil {
+			addr = tools.ConvertAddrToStr(nbr.MgmtAddr[:])
+		}
+		t.AppendRow(table.Row{
+			nbr.Intf,
+			nbr.SysName,
+			tools.ConvertAddrToStr(nbr.ChassisId[:]),
+			nbr.PortId,
+			addr,
+			fmt.Sprintf("%ds", int(time.Until(nbr.Expires).Seconds()))})
+	}
+	t.Render()
+}
+
+func dumpMonitorSessions(node *network.Node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Session", "Source", "Direction", "Destination", "Vlan"})
+	for _, session := range network.GetNodeMonitorSessions(node) {
+		vlan := "all"
+		if session.Vlan != 0 {
+			vlan = strconv.Itoa(int(session.Vlan))
+		}
+		for _, src := range session.Sources {
+			t.AppendRow(table.Row{session.Id, src.Intf, src.Dir, session.Destination, vlan})
+		}
 	}
 	t.Render()
 }
@@ -371,7 +380,7 @@ func dumpRoutingTable(node *network.Node) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
 	t.AppendHeader(table.Row{"Dst IpAddr", "Mask", "Direct", "Gateway IpAddr", "Outgoing Intf"})
-	for curr := network.GetNodeRoutingTable(node); curr != nil; curr = curr.Next {
+	for _, curr := range stack.GetRoutingTable(node) {
 		addr := "NA"
 		if curr.GatewayIp != nil {
 			addr = tools.ConvertAddrToStr(curr.GatewayIp.Addr[:])
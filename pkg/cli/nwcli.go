@@ -11,6 +11,72 @@ const (
 	L3_HANDLER     = 6
 	PING_HANDLER   = 7
 	ARPALL_HANDLER = 8
+	SOURCE_HANDLER = 9
+	SOURCE_CONT    = 10
+	TOPO_DOT       = 11
+	TOPO_ASCII     = 12
+	NODE_ADD       = 13
+	NODE_DEL       = 14
+	LINK_ADD       = 15
+	LINK_DEL       = 16
+	INTF_DEL       = 17
+	INTF_IP        = 18
+	INTF_L2MODE    = 19
+	INTF_VLAN      = 20
+	INTF_SHUT      = 21
+	INTF_NO_SHUT   = 22
+	LINK_DELAY     = 23
+	LINK_JITTER    = 24
+	LINK_LOSS      = 25
+	LINK_DUP       = 26
+	LINK_REORDER   = 27
+	LINK_BW        = 28
+	LINK_CLEAR     = 29
+	CAPTURE_START  = 30
+	CAPTURE_STOP   = 31
+	CAPTURE_ALL    = 32
+	CAPTURE_NONE   = 33
+	TRACE_HANDLER  = 34
+	ARP_STATIC     = 35
+	ARP_NO_STATIC  = 36
+	ARP_CLEAR      = 37
+	ARP_TIMEOUT    = 38
+	INTF_MAC       = 39
+	INTF_PROXY_ARP = 40
+	INTF_NO_PROXY  = 41
+	MAC_STATIC     = 42
+	MAC_NO_STATIC  = 43
+	MAC_CLEAR      = 44
+	MAC_TIMEOUT    = 45
+	STP_SHOW       = 46
+	STP_ENABLE     = 47
+	STP_DISABLE    = 48
+	STP_PRIORITY   = 49
+	INTF_NATIVE    = 50
+	INTF_CHANNEL   = 51
+	INTF_NO_CHAN   = 52
+	CHANNEL_SHOW   = 53
+	STORM_LEVEL    = 54
+	STORM_ACTION   = 55
+	STORM_NO       = 56
+	STORM_SHOW     = 57
+	STORM_CLEAR    = 58
+	PORTSEC_ENABLE = 59
+	PORTSEC_MAX    = 60
+	PORTSEC_VIOL   = 61
+	PORTSEC_STICKY = 62
+	PORTSEC_NO     = 63
+	PORTSEC_NOSTKY = 64
+	PORTSEC_SHOW   = 65
+	PORTSEC_CLEAR  = 66
+	MONITOR_ADD    = 67
+	MONITOR_NO     = 68
+	MONITOR_SHOW   = 69
+	LLDP_SHOW      = 70
+	LLDP_CLEAR     = 71
+	INTF_MTU       = 72
+	INTF_NO_MTU    = 73
+	INTF_NO_NATIVE = 74
 )
 
 func InitNwCli() {
@@ -19,6 +85,7 @@ func InitNwCli() {
 	show := cmdparser.GetShowHook()
 	run := cmdparser.GetRunHook()
 	config := cmdparser.GetConfigHook()
+	clear := cmdparser.GetClearHook()
 
 	{
 		var topo cmdparser.Param
@@ -32,6 +99,33 @@ func InitNwCli() {
 			"Dump entire network topology") // help string
 		cmdparser.LibcliRegisterParam(show, &topo)
 		cmdparser.SetParamCmdCode(&topo, SHOW_TOPO)
+
+		{
+			var dot cmdparser.Param
+			cmdparser.InitParam(&dot,
+				cmdparser.CMD,
+				"dot",
+				showHandler,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Dump topology in graphviz dot format")
+			cmdparser.LibcliRegisterParam(&topo, &dot)
+			cmdparser.SetParamCmdCode(&dot, TOPO_DOT)
+		}
+		{
+			var ascii cmdparser.Param
+			cmdparser.InitParam(&ascii,
+				cmdparser.CMD,
+				"ascii",
+				showHandler,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Draw topology as ascii diagram")
+			cmdparser.LibcliRegisterParam(&topo, &ascii)
+			cmdparser.SetParamCmdCode(&ascii, TOPO_ASCII)
+		}
 	}
 	{
 		var node cmdparser.Param
@@ -70,6 +164,84 @@ func InitNwCli() {
 				cmdparser.LibcliRegisterParam(&nodeName, &mac)
 				cmdparser.SetParamCmdCode(&mac, MAC_TABLE)
 			}
+			{
+				var stp cmdparser.Param
+				cmdparser.InitParam(&stp,
+					cmdparser.CMD,
+					"spanning-tree",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Spanning tree state of a node")
+				cmdparser.LibcliRegisterParam(&nodeName, &stp)
+				cmdparser.SetParamCmdCode(&stp, STP_SHOW)
+			}
+			{
+				var etherchannel cmdparser.Param
+				cmdparser.InitParam(&etherchannel,
+					cmdparser.CMD,
+					"etherchannel",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Port-channels of a node and the state of their members")
+				cmdparser.LibcliRegisterParam(&nodeName, &etherchannel)
+				cmdparser.SetParamCmdCode(&etherchannel, CHANNEL_SHOW)
+			}
+			{
+				var storm cmdparser.Param
+				cmdparser.InitParam(&storm,
+					cmdparser.CMD,
+					"storm-control",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Storm control levels and drops of the ports of a node")
+				cmdparser.LibcliRegisterParam(&nodeName, &storm)
+				cmdparser.SetParamCmdCode(&storm, STORM_SHOW)
+			}
+			{
+				var portSec cmdparser.Param
+				cmdparser.InitParam(&portSec,
+					cmdparser.CMD,
+					"port-security",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Port security of the access ports of a node")
+				cmdparser.LibcliRegisterParam(&nodeName, &portSec)
+				cmdparser.SetParamCmdCode(&portSec, PORTSEC_SHOW)
+			}
+			{
+				var monitor cmdparser.Param
+				cmdparser.InitParam(&monitor,
+					cmdparser.CMD,
+					"monitor",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Monitor sessions of a node")
+				cmdparser.LibcliRegisterParam(&nodeName, &monitor)
+				cmdparser.SetParamCmdCode(&monitor, MONITOR_SHOW)
+			}
+			{
+				var neighbors cmdparser.Param
+				cmdparser.InitParam(&neighbors,
+					cmdparser.CMD,
+					"neighbors",
+					showHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Neighbors of a node discovered with LLDP")
+				cmdparser.LibcliRegisterParam(&nodeName, &neighbors)
+				cmdparser.SetParamCmdCode(&neighbors, LLDP_SHOW)
+			}
 
 			{
 				var arp cmdparser.Param
@@ -163,6 +335,33 @@ func InitNwCli() {
 				}
 			}
 
+			{
+				var intf cmdparser.Param
+				cmdparser.InitParam(&intf,
+					cmdparser.CMD,
+					"interface",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Operate on an interface of the node")
+				cmdparser.LibcliRegisterParam(&nodeName, &intf)
+
+				{
+					var intfName cmdparser.Param
+					cmdparser.InitParam(&intfName,
+						cmdparser.LEAF,
+						"",
+						nil,
+						nil,
+						cmdparser.STRING,
+						"intf-name",
+						"Interface name")
+					cmdparser.LibcliRegisterParam(&intf, &intfName)
+
+					registerCaptureCmds(&intfName, CAPTURE_START, CAPTURE_STOP)
+				}
+			}
 			{
 				var ping cmdparser.Param
 				cmdparser.InitParam(&ping,
@@ -187,23 +386,214 @@ func InitNwCli() {
 						"Dst IPaddr for ping functionality")
 					cmdparser.LibcliRegisterParam(&ping, &ipAddr)
 					cmdparser.SetParamCmdCode(&ipAddr, PING_HANDLER)
+
+					// every option is optional on its own, their values lead to the other options so
+					// that they follow each other in any order
+					var count, countVal, size, sizeVal, timeout, timeoutVal, dfBit, dfBitVal cmdparser.Param
+					{
+						cmdparser.InitParam(&count,
+							cmdparser.CMD,
+							"count",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Number of echo requests to send")
+						cmdparser.LibcliRegisterParam(&ipAddr, &count)
+
+						cmdparser.InitParam(&countVal,
+							cmdparser.LEAF,
+							"",
+							pingHandler,
+							validCount,
+							cmdparser.INT,
+							"count",
+							"Number of echo requests to send")
+						cmdparser.LibcliRegisterParam(&count, &countVal)
+						cmdparser.SetParamCmdCode(&countVal, PING_HANDLER)
+					}
+					{
+						cmdparser.InitParam(&size,
+							cmdparser.CMD,
+							"size",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Bytes of data in every echo request")
+						cmdparser.LibcliRegisterParam(&ipAddr, &size)
+
+						cmdparser.InitParam(&sizeVal,
+							cmdparser.LEAF,
+							"",
+							pingHandler,
+							validPingSize,
+							cmdparser.INT,
+							"size",
+							"Bytes of data in every echo request")
+						cmdparser.LibcliRegisterParam(&size, &sizeVal)
+						cmdparser.SetParamCmdCode(&sizeVal, PING_HANDLER)
+					}
+					{
+						cmdparser.InitParam(&timeout,
+							cmdparser.CMD,
+							"timeout",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Seconds to wait for every reply")
+						cmdparser.LibcliRegisterParam(&ipAddr, &timeout)
+
+						cmdparser.InitParam(&timeoutVal,
+							cmdparser.LEAF,
+							"",
+							pingHandler,
+							validCount,
+							cmdparser.INT,
+							"timeout",
+							"Seconds to wait for every reply")
+						cmdparser.LibcliRegisterParam(&timeout, &timeoutVal)
+						cmdparser.SetParamCmdCode(&timeoutVal, PING_HANDLER)
+					}
+					{
+						cmdparser.InitParam(&dfBit,
+							cmdparser.CMD,
+							"df-bit",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Don't fragment the echo requests, on or off")
+						cmdparser.LibcliRegisterParam(&ipAddr, &dfBit)
+
+						cmdparser.InitParam(&dfBitVal,
+							cmdparser.LEAF,
+							"",
+							pingHandler,
+							validOnOff,
+							cmdparser.STRING,
+							"df-bit",
+							"Don't fragment the echo requests, on or off")
+						cmdparser.LibcliRegisterParam(&dfBit, &dfBitVal)
+						cmdparser.SetParamCmdCode(&dfBitVal, PING_HANDLER)
+					}
+					for _, value := range []*cmdparser.Param{&countVal, &sizeVal, &timeoutVal, &dfBitVal} {
+						for _, option := range []*cmdparser.Param{&count, &size, &timeout, &dfBit} {
+							cmdparser.LibcliRegisterParam(value, option)
+						}
+					}
+				}
+			}
+			{
+				var traceroute cmdparser.Param
+				cmdparser.InitParam(&traceroute,
+					cmdparser.CMD,
+					"traceroute",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Print the routers on the way to a destination")
+				cmdparser.LibcliRegisterParam(&nodeName, &traceroute)
+
+				{
+					var ipAddr cmdparser.Param
+					cmdparser.InitParam(&ipAddr,
+						cmdparser.LEAF,
+						"",
+						pingHandler,
+						validIPAddr,
+						cmdparser.STRING,
+						"ip-addr",
+						"Dst IPaddr of the traceroute")
+					cmdparser.LibcliRegisterParam(&traceroute, &ipAddr)
+					cmdparser.SetParamCmdCode(&ipAddr, TRACE_HANDLER)
+
+					{
+						var maxHops cmdparser.Param
+						cmdparser.InitParam(&maxHops,
+							cmdparser.CMD,
+							"max-hops",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Largest TTL probed")
+						cmdparser.LibcliRegisterParam(&ipAddr, &maxHops)
+
+						{
+							var value cmdparser.Param
+							cmdparser.InitParam(&value,
+								cmdparser.LEAF,
+								"",
+								pingHandler,
+								validTtl,
+								cmdparser.INT,
+								"max-hops",
+								"Largest TTL probed")
+							cmdparser.LibcliRegisterParam(&maxHops, &value)
+							cmdparser.SetParamCmdCode(&value, TRACE_HANDLER)
+						}
+					}
 				}
 			}
 
 		}
 
 	}
+	registerCaptureCmds(run, CAPTURE_ALL, CAPTURE_NONE)
 	{
-		var node cmdparser.Param
-		cmdparser.InitParam(&node,
+		var source cmdparser.Param
+		cmdparser.InitParam(&source,
 			cmdparser.CMD,
-			"node",
+			"source",
 			nil,
 			nil,
 			cmdparser.INVALID,
 			"",
-			"Given a node name and operation it performs that operation")
-		cmdparser.LibcliRegisterParam(config, &node)
+			"Execute the commands of a script file")
+		cmdparser.LibcliRegisterParam(run, &source)
+
+		{
+			var file cmdparser.Param
+			cmdparser.InitParam(&file,
+				cmdparser.LEAF,
+				"",
+				sourceHandler,
+				nil,
+				cmdparser.STRING,
+				"file",
+				"Path of the script file")
+			cmdparser.LibcliRegisterParam(&source, &file)
+			cmdparser.SetParamCmdCode(&file, SOURCE_HANDLER)
+
+			{
+				var cont cmdparser.Param
+				cmdparser.InitParam(&cont,
+					cmdparser.CMD,
+					"continue-on-error",
+					sourceHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Keep executing the script when a command fails")
+				cmdparser.LibcliRegisterParam(&file, &cont)
+				cmdparser.SetParamCmdCode(&cont, SOURCE_CONT)
+			}
+		}
+	}
+	{
+		var link cmdparser.Param
+		cmdparser.InitParam(&link,
+			cmdparser.CMD,
+			"link",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Configure impairments of the link attached to an interface")
+		cmdparser.LibcliRegisterParam(config, &link)
 
 		{
 			var nodeName cmdparser.Param
@@ -215,72 +605,1842 @@ func InitNwCli() {
 				cmdparser.STRING,
 				"node-name",
 				"Name of a node in the topology")
-			cmdparser.LibcliRegisterParam(&node, &nodeName)
+			cmdparser.LibcliRegisterParam(&link, &nodeName)
 
 			{
-				var route cmdparser.Param
-				cmdparser.InitParam(&route,
-					cmdparser.CMD,
-					"route",
+				var intfName cmdparser.Param
+				cmdparser.InitParam(&intfName,
+					cmdparser.LEAF,
+					"",
 					nil,
 					nil,
-					cmdparser.INVALID,
-					"",
-					"Routing Table")
-				cmdparser.LibcliRegisterParam(&nodeName, &route)
+					cmdparser.STRING,
+					"intf-name",
+					"Interface at one end of the link")
+				cmdparser.LibcliRegisterParam(&nodeName, &intfName)
 
 				{
-					var dst cmdparser.Param
-					cmdparser.InitParam(&dst,
-						cmdparser.LEAF,
+					var delay cmdparser.Param
+					cmdparser.InitParam(&delay,
+						cmdparser.CMD,
+						"delay",
+						nil,
+						nil,
+						cmdparser.INVALID,
 						"",
+						"Fixed delay in milliseconds")
+					cmdparser.LibcliRegisterParam(&intfName, &delay)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							linkConfigHandler,
+							validMs,
+							cmdparser.FLOAT,
+							"value",
+							"Fixed delay in milliseconds")
+						cmdparser.LibcliRegisterParam(&delay, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_DELAY)
+					}
+				}
+				{
+					var jitter cmdparser.Param
+					cmdparser.InitParam(&jitter,
+						cmdparser.CMD,
+						"jitter",
 						nil,
-						validIPAddr,
-						cmdparser.STRING,
-						"dst",
-						"Destination Ip Addr")
-					cmdparser.LibcliRegisterParam(&route, &dst)
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Jitter in milliseconds")
+					cmdparser.LibcliRegisterParam(&intfName, &jitter)
 
 					{
-						var mask cmdparser.Param
-						cmdparser.InitParam(&mask,
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
 							cmdparser.LEAF,
 							"",
-							nil,
-							validMask,
-							cmdparser.STRING,
-							"mask",
-							"Mask of Ip Addr")
-						cmdparser.LibcliRegisterParam(&dst, &mask)
+							linkConfigHandler,
+							validMs,
+							cmdparser.FLOAT,
+							"value",
+							"Jitter in milliseconds")
+						cmdparser.LibcliRegisterParam(&jitter, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_JITTER)
 
 						{
-							var gatewayIP cmdparser.Param
-							cmdparser.InitParam(&gatewayIP,
+							var dist cmdparser.Param
+							cmdparser.InitParam(&dist,
 								cmdparser.LEAF,
 								"",
-								nil,
-								validIPAddr,
+								linkConfigHandler,
+								validJitterDist,
 								cmdparser.STRING,
-								"gw-ip",
-								"Gateway IP Addr")
-							cmdparser.LibcliRegisterParam(&mask, &gatewayIP)
-
-							{
-								var outIntf cmdparser.Param
-								cmdparser.InitParam(&outIntf,
-									cmdparser.LEAF,
-									"",
-									l3ConfigHandler,
-									nil,
-									cmdparser.STRING,
-									"out-intf",
-									"Outgoing interface")
-								cmdparser.LibcliRegisterParam(&gatewayIP, &outIntf)
-								cmdparser.SetParamCmdCode(&outIntf, L3_HANDLER)
-							}
+								"jitter-dist",
+								"uniform or normal distribution")
+							cmdparser.LibcliRegisterParam(&value, &dist)
+							cmdparser.SetParamCmdCode(&dist, LINK_JITTER)
 						}
 					}
 				}
+				{
+					var loss cmdparser.Param
+					cmdparser.InitParam(&loss,
+						cmdparser.CMD,
+						"loss",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Percentage of frames lost")
+					cmdparser.LibcliRegisterParam(&intfName, &loss)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							linkConfigHandler,
+							validPercent,
+							cmdparser.FLOAT,
+							"value",
+							"Percentage of frames lost")
+						cmdparser.LibcliRegisterParam(&loss, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_LOSS)
+					}
+				}
+				{
+					var duplicate cmdparser.Param
+					cmdparser.InitParam(&duplicate,
+						cmdparser.CMD,
+						"duplicate",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Percentage of frames duplicated")
+					cmdparser.LibcliRegisterParam(&intfName, &duplicate)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							linkConfigHandler,
+							validPercent,
+							cmdparser.FLOAT,
+							"value",
+							"Percentage of frames duplicated")
+						cmdparser.LibcliRegisterParam(&duplicate, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_DUP)
+					}
+				}
+				{
+					var reorder cmdparser.Param
+					cmdparser.InitParam(&reorder,
+						cmdparser.CMD,
+						"reorder",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Percentage of frames reordered")
+					cmdparser.LibcliRegisterParam(&intfName, &reorder)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							linkConfigHandler,
+							validPercent,
+							cmdparser.FLOAT,
+							"value",
+							"Percentage of frames reordered")
+						cmdparser.LibcliRegisterParam(&reorder, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_REORDER)
+					}
+				}
+				{
+					var bandwidth cmdparser.Param
+					cmdparser.InitParam(&bandwidth,
+						cmdparser.CMD,
+						"bandwidth",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Bandwidth cap in kbps, 0 for unlimited")
+					cmdparser.LibcliRegisterParam(&intfName, &bandwidth)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							linkConfigHandler,
+							validCost,
+							cmdparser.INT,
+							"value",
+							"Bandwidth cap in kbps, 0 for unlimited")
+						cmdparser.LibcliRegisterParam(&bandwidth, &value)
+						cmdparser.SetParamCmdCode(&value, LINK_BW)
+					}
+				}
+
+				{
+					var clear cmdparser.Param
+					cmdparser.InitParam(&clear,
+						cmdparser.CMD,
+						"clear",
+						linkConfigHandler,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Remove all impairments of the link")
+					cmdparser.LibcliRegisterParam(&intfName, &clear)
+					cmdparser.SetParamCmdCode(&clear, LINK_CLEAR)
+				}
+			}
+		}
+	}
+	{
+		var add cmdparser.Param
+		cmdparser.InitParam(&add,
+			cmdparser.CMD,
+			"add",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Add an object to the topology")
+		cmdparser.LibcliRegisterParam(config, &add)
+
+		{
+			var node cmdparser.Param
+			cmdparser.InitParam(&node,
+				cmdparser.CMD,
+				"node",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Add a node to the topology")
+			cmdparser.LibcliRegisterParam(&add, &node)
+
+			{
+				var nodeName cmdparser.Param
+				cmdparser.InitParam(&nodeName,
+					cmdparser.LEAF,
+					"",
+					topoConfigHandler,
+					nil,
+					cmdparser.STRING,
+					"new-node",
+					"Name of the new node")
+				cmdparser.LibcliRegisterParam(&node, &nodeName)
+				cmdparser.SetParamCmdCode(&nodeName, NODE_ADD)
+
+				{
+					var loopback cmdparser.Param
+					cmdparser.InitParam(&loopback,
+						cmdparser.CMD,
+						"loopback",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Loopback addr of the new node")
+					cmdparser.LibcliRegisterParam(&nodeName, &loopback)
+
+					{
+						var ipAddr cmdparser.Param
+						cmdparser.InitParam(&ipAddr,
+							cmdparser.LEAF,
+							"",
+							topoConfigHandler,
+							validIPAddr,
+							cmdparser.STRING,
+							"ip-addr",
+							"Loopback ip addr")
+						cmdparser.LibcliRegisterParam(&loopback, &ipAddr)
+						cmdparser.SetParamCmdCode(&ipAddr, NODE_ADD)
+					}
+				}
+			}
+		}
+	}
+	{
+		var no cmdparser.Param
+		cmdparser.InitParam(&no,
+			cmdparser.CMD,
+			"no",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Remove an object from the topology")
+		cmdparser.LibcliRegisterParam(config, &no)
+
+		{
+			var node cmdparser.Param
+			cmdparser.InitParam(&node,
+				cmdparser.CMD,
+				"node",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Remove a node from the topology")
+			cmdparser.LibcliRegisterParam(&no, &node)
+
+			{
+				var nodeName cmdparser.Param
+				cmdparser.InitParam(&nodeName,
+					cmdparser.LEAF,
+					"",
+					topoConfigHandler,
+					validNodeName,
+					cmdparser.STRING,
+					"node-name",
+					"Name of a node in the topology")
+				cmdparser.LibcliRegisterParam(&node, &nodeName)
+				cmdparser.SetParamCmdCode(&nodeName, NODE_DEL)
+			}
+		}
+	}
+	{
+		var node cmdparser.Param
+		cmdparser.InitParam(&node,
+			cmdparser.CMD,
+			"node",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Given a node name and operation it performs that operation")
+		cmdparser.LibcliRegisterParam(config, &node)
+
+		{
+			var nodeName cmdparser.Param
+			cmdparser.InitParam(&nodeName,
+				cmdparser.LEAF,
+				"",
+				nil,
+				validNodeName,
+				cmdparser.STRING,
+				"node-name",
+				"Name of a node in the topology")
+			cmdparser.LibcliRegisterParam(&node, &nodeName)
+
+			{
+				var link cmdparser.Param
+				cmdparser.InitParam(&link,
+					cmdparser.CMD,
+					"link",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Connect an interface of the node to an interface of another node")
+				cmdparser.LibcliRegisterParam(&nodeName, &link)
+
+				{
+					var intfName cmdparser.Param
+					cmdparser.InitParam(&intfName,
+						cmdparser.LEAF,
+						"",
+						nil,
+						nil,
+						cmdparser.STRING,
+						"intf-name",
+						"Local interface name")
+					cmdparser.LibcliRegisterParam(&link, &intfName)
+
+					{
+						var peerNode cmdparser.Param
+						cmdparser.InitParam(&peerNode,
+							cmdparser.LEAF,
+							"",
+							nil,
+							validNodeName,
+							cmdparser.STRING,
+							"peer-node",
+							"Name of the peer node")
+						cmdparser.LibcliRegisterParam(&intfName, &peerNode)
+
+						{
+							var peerIntf cmdparser.Param
+							cmdparser.InitParam(&peerIntf,
+								cmdparser.LEAF,
+								"",
+								topoConfigHandler,
+								nil,
+								cmdparser.STRING,
+								"peer-intf",
+								"Interface name on the peer node")
+							cmdparser.LibcliRegisterParam(&peerNode, &peerIntf)
+							cmdparser.SetParamCmdCode(&peerIntf, LINK_ADD)
+
+							{
+								var cost cmdparser.Param
+								cmdparser.InitParam(&cost,
+									cmdparser.CMD,
+									"cost",
+									nil,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Cost of the link")
+								cmdparser.LibcliRegisterParam(&peerIntf, &cost)
+
+								{
+									var value cmdparser.Param
+									cmdparser.InitParam(&value,
+										cmdparser.LEAF,
+										"",
+										topoConfigHandler,
+										validCost,
+										cmdparser.INT,
+										"cost",
+										"Cost value")
+									cmdparser.LibcliRegisterParam(&cost, &value)
+									cmdparser.SetParamCmdCode(&value, LINK_ADD)
+								}
+							}
+						}
+					}
+				}
+			}
+			{
+				var intf cmdparser.Param
+				cmdparser.InitParam(&intf,
+					cmdparser.CMD,
+					"interface",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Configure an interface of the node")
+				cmdparser.LibcliRegisterParam(&nodeName, &intf)
+
+				{
+					var intfName cmdparser.Param
+					cmdparser.InitParam(&intfName,
+						cmdparser.LEAF,
+						"",
+						nil,
+						nil,
+						cmdparser.STRING,
+						"intf-name",
+						"Interface name")
+					cmdparser.LibcliRegisterParam(&intf, &intfName)
+
+					{
+						var shutdown cmdparser.Param
+						cmdparser.InitParam(&shutdown,
+							cmdparser.CMD,
+							"shutdown",
+							topoConfigHandler,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Administratively shut the interface down")
+						cmdparser.LibcliRegisterParam(&intfName, &shutdown)
+						cmdparser.SetParamCmdCode(&shutdown, INTF_SHUT)
+					}
+					{
+						var proxyArp cmdparser.Param
+						cmdparser.InitParam(&proxyArp,
+							cmdparser.CMD,
+							"proxy-arp",
+							topoConfigHandler,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Answer arp requests for addrs routed through another interface")
+						cmdparser.LibcliRegisterParam(&intfName, &proxyArp)
+						cmdparser.SetParamCmdCode(&proxyArp, INTF_PROXY_ARP)
+					}
+					{
+						var mtu cmdparser.Param
+						cmdparser.InitParam(&mtu,
+							cmdparser.CMD,
+							"mtu",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Largest payload of the frames sent and received")
+						cmdparser.LibcliRegisterParam(&intfName, &mtu)
+
+						{
+							var bytes cmdparser.Param
+							cmdparser.InitParam(&bytes,
+								cmdparser.LEAF,
+								"",
+								topoConfigHandler,
+								validMtu,
+								cmdparser.INT,
+								"mtu",
+								"Bytes, between 68 and 9216")
+							cmdparser.LibcliRegisterParam(&mtu, &bytes)
+							cmdparser.SetParamCmdCode(&bytes, INTF_MTU)
+						}
+					}
+					{
+						var no cmdparser.Param
+						cmdparser.InitParam(&no,
+							cmdparser.CMD,
+							"no",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Negate a configuration of the interface")
+						cmdparser.LibcliRegisterParam(&intfName, &no)
+
+						{
+							var shutdown cmdparser.Param
+							cmdparser.InitParam(&shutdown,
+								cmdparser.CMD,
+								"shutdown",
+								topoConfigHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Administratively bring the interface up")
+							cmdparser.LibcliRegisterParam(&no, &shutdown)
+							cmdparser.SetParamCmdCode(&shutdown, INTF_NO_SHUT)
+						}
+						{
+							var proxyArp cmdparser.Param
+							cmdparser.InitParam(&proxyArp,
+								cmdparser.CMD,
+								"proxy-arp",
+								topoConfigHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Stop answering arp requests on behalf of other hosts")
+							cmdparser.LibcliRegisterParam(&no, &proxyArp)
+							cmdparser.SetParamCmdCode(&proxyArp, INTF_NO_PROXY)
+						}
+						{
+							var mtu cmdparser.Param
+							cmdparser.InitParam(&mtu,
+								cmdparser.CMD,
+								"mtu",
+								topoConfigHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Restore the default mtu of 1500 bytes")
+							cmdparser.LibcliRegisterParam(&no, &mtu)
+							cmdparser.SetParamCmdCode(&mtu, INTF_NO_MTU)
+						}
+						{
+							var channelGroup cmdparser.Param
+							cmdparser.InitParam(&channelGroup,
+								cmdparser.CMD,
+								"channel-group",
+								topoConfigHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Take the interface out of its port-channel")
+							cmdparser.LibcliRegisterParam(&no, &channelGroup)
+							cmdparser.SetParamCmdCode(&channelGroup, INTF_NO_CHAN)
+						}
+						{
+							var trunk cmdparser.Param
+							cmdparser.InitParam(&trunk,
+								cmdparser.CMD,
+								"trunk",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Trunk settings of the interface")
+							cmdparser.LibcliRegisterParam(&no, &trunk)
+
+							{
+								var native cmdparser.Param
+								cmdparser.InitParam(&native,
+									cmdparser.CMD,
+									"native-vlan",
+									topoConfigHandler,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Drop the untagged frames crossing the trunk")
+								cmdparser.LibcliRegisterParam(&trunk, &native)
+								cmdparser.SetParamCmdCode(&native, INTF_NO_NATIVE)
+							}
+						}
+						{
+							var storm cmdparser.Param
+							cmdparser.InitParam(&storm,
+								cmdparser.CMD,
+								"storm-control",
+								stormHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Let every flooded frame in")
+							cmdparser.LibcliRegisterParam(&no, &storm)
+							cmdparser.SetParamCmdCode(&storm, STORM_NO)
+
+							{
+								var stormType cmdparser.Param
+								cmdparser.InitParam(&stormType,
+									cmdparser.LEAF,
+									"",
+									stormHandler,
+									validStormType,
+									cmdparser.STRING,
+									"storm-type",
+									"Remove only the level of this type")
+								cmdparser.LibcliRegisterParam(&storm, &stormType)
+								cmdparser.SetParamCmdCode(&stormType, STORM_NO)
+							}
+						}
+						{
+							var portSec cmdparser.Param
+							cmdparser.InitParam(&portSec,
+								cmdparser.CMD,
+								"port-security",
+								portSecHandler,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Let the port learn any mac addr")
+							cmdparser.LibcliRegisterParam(&no, &portSec)
+							cmdparser.SetParamCmdCode(&portSec, PORTSEC_NO)
+
+							{
+								var macAddr cmdparser.Param
+								cmdparser.InitParam(&macAddr,
+									cmdparser.CMD,
+									"mac-address",
+									nil,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Secure mac addrs of the port")
+								cmdparser.LibcliRegisterParam(&portSec, &macAddr)
+
+								{
+									var sticky cmdparser.Param
+									cmdparser.InitParam(&sticky,
+										cmdparser.CMD,
+										"sticky",
+										portSecHandler,
+										nil,
+										cmdparser.INVALID,
+										"",
+										"Stop saving the learned mac addrs, the saved ones are forgotten")
+									cmdparser.LibcliRegisterParam(&macAddr, &sticky)
+									cmdparser.SetParamCmdCode(&sticky, PORTSEC_NOSTKY)
+								}
+							}
+						}
+					}
+					{
+						var ip cmdparser.Param
+						cmdparser.InitParam(&ip,
+							cmdparser.CMD,
+							"ip",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Assign an ip addr to the interface")
+						cmdparser.LibcliRegisterParam(&intfName, &ip)
+
+						{
+							var ipAddr cmdparser.Param
+							cmdparser.InitParam(&ipAddr,
+								cmdparser.LEAF,
+								"",
+								nil,
+								validIPAddr,
+								cmdparser.STRING,
+								"ip-addr",
+								"Ip addr of the interface")
+							cmdparser.LibcliRegisterParam(&ip, &ipAddr)
+
+							{
+								var mask cmdparser.Param
+								cmdparser.InitParam(&mask,
+									cmdparser.LEAF,
+									"",
+									topoConfigHandler,
+									validMask,
+									cmdparser.STRING,
+									"mask",
+									"Mask of Ip Addr")
+								cmdparser.LibcliRegisterParam(&ipAddr, &mask)
+								cmdparser.SetParamCmdCode(&mask, INTF_IP)
+							}
+						}
+					}
+					{
+						var mac cmdparser.Param
+						cmdparser.InitParam(&mac,
+							cmdparser.CMD,
+							"mac",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Change the mac addr of the interface")
+						cmdparser.LibcliRegisterParam(&intfName, &mac)
+
+						{
+							var macAddr cmdparser.Param
+							cmdparser.InitParam(&macAddr,
+								cmdparser.LEAF,
+								"",
+								topoConfigHandler,
+								validMacAddr,
+								cmdparser.STRING,
+								"mac-addr",
+								"Mac addr, i.e aa:bb:cc:dd:ee:ff")
+							cmdparser.LibcliRegisterParam(&mac, &macAddr)
+							cmdparser.SetParamCmdCode(&macAddr, INTF_MAC)
+						}
+					}
+					{
+						var l2Mode cmdparser.Param
+						cmdparser.InitParam(&l2Mode,
+							cmdparser.CMD,
+							"l2mode",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Put the interface in L2 mode")
+						cmdparser.LibcliRegisterParam(&intfName, &l2Mode)
+
+						{
+							var mode cmdparser.Param
+							cmdparser.InitParam(&mode,
+								cmdparser.LEAF,
+								"",
+								topoConfigHandler,
+								validL2Mode,
+								cmdparser.STRING,
+								"l2-mode",
+								"access or trunk")
+							cmdparser.LibcliRegisterParam(&l2Mode, &mode)
+							cmdparser.SetParamCmdCode(&mode, INTF_L2MODE)
+						}
+					}
+					{
+						var vlan cmdparser.Param
+						cmdparser.InitParam(&vlan,
+							cmdparser.CMD,
+							"vlan",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Add the interface to a vlan")
+						cmdparser.LibcliRegisterParam(&intfName, &vlan)
+
+						{
+							var vlanId cmdparser.Param
+							cmdparser.InitParam(&vlanId,
+								cmdparser.LEAF,
+								"",
+								topoConfigHandler,
+								validVlan,
+								cmdparser.INT,
+								"vlan-id",
+								"Vlan id")
+							cmdparser.LibcliRegisterParam(&vlan, &vlanId)
+							cmdparser.SetParamCmdCode(&vlanId, INTF_VLAN)
+						}
+					}
+					{
+						var trunk cmdparser.Param
+						cmdparser.InitParam(&trunk,
+							cmdparser.CMD,
+							"trunk",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Trunk settings of the interface")
+						cmdparser.LibcliRegisterParam(&intfName, &trunk)
+
+						{
+							var native cmdparser.Param
+							cmdparser.InitParam(&native,
+								cmdparser.CMD,
+								"native-vlan",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Vlan of the untagged frames crossing the trunk")
+							cmdparser.LibcliRegisterParam(&trunk, &native)
+
+							{
+								var vlanId cmdparser.Param
+								cmdparser.InitParam(&vlanId,
+									cmdparser.LEAF,
+									"",
+									topoConfigHandler,
+									validVlan,
+									cmdparser.INT,
+									"vlan-id",
+									"Vlan id")
+								cmdparser.LibcliRegisterParam(&native, &vlanId)
+								cmdparser.SetParamCmdCode(&vlanId, INTF_NATIVE)
+							}
+						}
+					}
+					{
+						var channelGroup cmdparser.Param
+						cmdparser.InitParam(&channelGroup,
+							cmdparser.CMD,
+							"channel-group",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Bundle the interface into a port-channel")
+						cmdparser.LibcliRegisterParam(&intfName, &channelGroup)
+
+						{
+							var channelId cmdparser.Param
+							cmdparser.InitParam(&channelId,
+								cmdparser.LEAF,
+								"",
+								nil,
+								validChannelId,
+								cmdparser.INT,
+								"channel-id",
+								"Port-channel id")
+							cmdparser.LibcliRegisterParam(&channelGroup, &channelId)
+
+							{
+								var mode cmdparser.Param
+								cmdparser.InitParam(&mode,
+									cmdparser.CMD,
+									"mode",
+									nil,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Bundling mode of the interface")
+								cmdparser.LibcliRegisterParam(&channelId, &mode)
+
+								{
+									var channelMode cmdparser.Param
+									cmdparser.InitParam(&channelMode,
+										cmdparser.LEAF,
+										"",
+										topoConfigHandler,
+										validChannelMode,
+										cmdparser.STRING,
+										"channel-mode",
+										"on, active or passive")
+									cmdparser.LibcliRegisterParam(&mode, &channelMode)
+									cmdparser.SetParamCmdCode(&channelMode, INTF_CHANNEL)
+								}
+							}
+						}
+					}
+					{
+						var storm cmdparser.Param
+						cmdparser.InitParam(&storm,
+							cmdparser.CMD,
+							"storm-control",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Limit the flooded frames let in by the port")
+						cmdparser.LibcliRegisterParam(&intfName, &storm)
+
+						{
+							var action cmdparser.Param
+							cmdparser.InitParam(&action,
+								cmdparser.CMD,
+								"action",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"What happens to the port when a level is exceeded")
+							cmdparser.LibcliRegisterParam(&storm, &action)
+
+							{
+								var stormAction cmdparser.Param
+								cmdparser.InitParam(&stormAction,
+									cmdparser.LEAF,
+									"",
+									stormHandler,
+									validStormAction,
+									cmdparser.STRING,
+									"storm-action",
+									"drop or shutdown")
+								cmdparser.LibcliRegisterParam(&action, &stormAction)
+								cmdparser.SetParamCmdCode(&stormAction, STORM_ACTION)
+							}
+						}
+						{
+							var stormType cmdparser.Param
+							cmdparser.InitParam(&stormType,
+								cmdparser.LEAF,
+								"",
+								nil,
+								validStormType,
+								cmdparser.STRING,
+								"storm-type",
+								"broadcast, multicast or unicast")
+							cmdparser.LibcliRegisterParam(&storm, &stormType)
+
+							{
+								var level cmdparser.Param
+								cmdparser.InitParam(&level,
+									cmdparser.CMD,
+									"level",
+									nil,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Packets per second let in")
+								cmdparser.LibcliRegisterParam(&stormType, &level)
+
+								{
+									var pps cmdparser.Param
+									cmdparser.InitParam(&pps,
+										cmdparser.LEAF,
+										"",
+										stormHandler,
+										validPps,
+										cmdparser.INT,
+										"pps",
+										"Packets per second")
+									cmdparser.LibcliRegisterParam(&level, &pps)
+									cmdparser.SetParamCmdCode(&pps, STORM_LEVEL)
+								}
+							}
+						}
+					}
+					{
+						var portSec cmdparser.Param
+						cmdparser.InitParam(&portSec,
+							cmdparser.CMD,
+							"port-security",
+							portSecHandler,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Limit the mac addrs learned on the access port")
+						cmdparser.LibcliRegisterParam(&intfName, &portSec)
+						cmdparser.SetParamCmdCode(&portSec, PORTSEC_ENABLE)
+
+						{
+							var maximum cmdparser.Param
+							cmdparser.InitParam(&maximum,
+								cmdparser.CMD,
+								"maximum",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Mac addrs the port can learn")
+							cmdparser.LibcliRegisterParam(&portSec, &maximum)
+
+							{
+								var count cmdparser.Param
+								cmdparser.InitParam(&count,
+									cmdparser.LEAF,
+									"",
+									portSecHandler,
+									validPortSecMaximum,
+									cmdparser.INT,
+									"max-macs",
+									"Between 1 and 1024")
+								cmdparser.LibcliRegisterParam(&maximum, &count)
+								cmdparser.SetParamCmdCode(&count, PORTSEC_MAX)
+							}
+						}
+						{
+							var violation cmdparser.Param
+							cmdparser.InitParam(&violation,
+								cmdparser.CMD,
+								"violation",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"What happens to frames from one mac addr too many")
+							cmdparser.LibcliRegisterParam(&portSec, &violation)
+
+							{
+								var mode cmdparser.Param
+								cmdparser.InitParam(&mode,
+									cmdparser.LEAF,
+									"",
+									portSecHandler,
+									validPortSecViolation,
+									cmdparser.STRING,
+									"violation-mode",
+									"protect, restrict or shutdown")
+								cmdparser.LibcliRegisterParam(&violation, &mode)
+								cmdparser.SetParamCmdCode(&mode, PORTSEC_VIOL)
+							}
+						}
+						{
+							var macAddr cmdparser.Param
+							cmdparser.InitParam(&macAddr,
+								cmdparser.CMD,
+								"mac-address",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Secure mac addrs of the port")
+							cmdparser.LibcliRegisterParam(&portSec, &macAddr)
+
+							{
+								var sticky cmdparser.Param
+								cmdparser.InitParam(&sticky,
+									cmdparser.CMD,
+									"sticky",
+									portSecHandler,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Save the learned mac addrs into the config of the port")
+								cmdparser.LibcliRegisterParam(&macAddr, &sticky)
+								cmdparser.SetParamCmdCode(&sticky, PORTSEC_STICKY)
+							}
+						}
+					}
+				}
+			}
+			{
+				var no cmdparser.Param
+				cmdparser.InitParam(&no,
+					cmdparser.CMD,
+					"no",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Negate a configuration of the node")
+				cmdparser.LibcliRegisterParam(&nodeName, &no)
+
+				{
+					var link cmdparser.Param
+					cmdparser.InitParam(&link,
+						cmdparser.CMD,
+						"link",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Disconnect the link of an interface")
+					cmdparser.LibcliRegisterParam(&no, &link)
+
+					{
+						var intfName cmdparser.Param
+						cmdparser.InitParam(&intfName,
+							cmdparser.LEAF,
+							"",
+							topoConfigHandler,
+							nil,
+							cmdparser.STRING,
+							"intf-name",
+							"Local interface name")
+						cmdparser.LibcliRegisterParam(&link, &intfName)
+						cmdparser.SetParamCmdCode(&intfName, LINK_DEL)
+					}
+				}
+				{
+					var intf cmdparser.Param
+					cmdparser.InitParam(&intf,
+						cmdparser.CMD,
+						"interface",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Delete an interface of the node")
+					cmdparser.LibcliRegisterParam(&no, &intf)
+
+					{
+						var intfName cmdparser.Param
+						cmdparser.InitParam(&intfName,
+							cmdparser.LEAF,
+							"",
+							topoConfigHandler,
+							nil,
+							cmdparser.STRING,
+							"intf-name",
+							"Interface name")
+						cmdparser.LibcliRegisterParam(&intf, &intfName)
+						cmdparser.SetParamCmdCode(&intfName, INTF_DEL)
+					}
+				}
+				{
+					var mac cmdparser.Param
+					cmdparser.InitParam(&mac,
+						cmdparser.CMD,
+						"mac",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Delete a static mac entry")
+					cmdparser.LibcliRegisterParam(&no, &mac)
+
+					{
+						var macAddr cmdparser.Param
+						cmdparser.InitParam(&macAddr,
+							cmdparser.LEAF,
+							"",
+							nil,
+							validMacAddr,
+							cmdparser.STRING,
+							"mac-addr",
+							"Mac addr of the host")
+						cmdparser.LibcliRegisterParam(&mac, &macAddr)
+
+						{
+							var vlan cmdparser.Param
+							cmdparser.InitParam(&vlan,
+								cmdparser.CMD,
+								"vlan",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Vlan of the host")
+							cmdparser.LibcliRegisterParam(&macAddr, &vlan)
+
+							{
+								var vlanId cmdparser.Param
+								cmdparser.InitParam(&vlanId,
+									cmdparser.LEAF,
+									"",
+									macHandler,
+									validVlan,
+									cmdparser.INT,
+									"vlan-id",
+									"Vlan id")
+								cmdparser.LibcliRegisterParam(&vlan, &vlanId)
+								cmdparser.SetParamCmdCode(&vlanId, MAC_NO_STATIC)
+							}
+						}
+					}
+				}
+				{
+					var arp cmdparser.Param
+					cmdparser.InitParam(&arp,
+						cmdparser.CMD,
+						"arp",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Delete a static arp entry")
+					cmdparser.LibcliRegisterParam(&no, &arp)
+
+					{
+						var ipAddr cmdparser.Param
+						cmdparser.InitParam(&ipAddr,
+							cmdparser.LEAF,
+							"",
+							arpHandler,
+							validIPAddr,
+							cmdparser.STRING,
+							"ip-addr",
+							"Ip addr of the neighbor")
+						cmdparser.LibcliRegisterParam(&arp, &ipAddr)
+						cmdparser.SetParamCmdCode(&ipAddr, ARP_NO_STATIC)
+					}
+				}
+				{
+					var stp cmdparser.Param
+					cmdparser.InitParam(&stp,
+						cmdparser.CMD,
+						"spanning-tree",
+						stpHandler,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Stop running the spanning tree, every L2 port forwards")
+					cmdparser.LibcliRegisterParam(&no, &stp)
+					cmdparser.SetParamCmdCode(&stp, STP_DISABLE)
+				}
+				{
+					var monitor cmdparser.Param
+					cmdparser.InitParam(&monitor,
+						cmdparser.CMD,
+						"monitor",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Delete a monitor session")
+					cmdparser.LibcliRegisterParam(&no, &monitor)
+
+					{
+						var session cmdparser.Param
+						cmdparser.InitParam(&session,
+							cmdparser.CMD,
+							"session",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Monitor session")
+						cmdparser.LibcliRegisterParam(&monitor, &session)
+
+						{
+							var id cmdparser.Param
+							cmdparser.InitParam(&id,
+								cmdparser.LEAF,
+								"",
+								monitorHandler,
+								validMonitorSession,
+								cmdparser.INT,
+								"session-id",
+								"Id of the session")
+							cmdparser.LibcliRegisterParam(&session, &id)
+							cmdparser.SetParamCmdCode(&id, MONITOR_NO)
+						}
+					}
+				}
+			}
+			{
+				var stp cmdparser.Param
+				cmdparser.InitParam(&stp,
+					cmdparser.CMD,
+					"spanning-tree",
+					stpHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Run the spanning tree on the L2 ports of the node")
+				cmdparser.LibcliRegisterParam(&nodeName, &stp)
+				cmdparser.SetParamCmdCode(&stp, STP_ENABLE)
+
+				{
+					var priority cmdparser.Param
+					cmdparser.InitParam(&priority,
+						cmdparser.CMD,
+						"priority",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Bridge priority, the lowest bridge id becomes the root")
+					cmdparser.LibcliRegisterParam(&stp, &priority)
+
+					{
+						var value cmdparser.Param
+						cmdparser.InitParam(&value,
+							cmdparser.LEAF,
+							"",
+							stpHandler,
+							validStpPriority,
+							cmdparser.INT,
+							"priority",
+							"Multiple of 4096 between 0 and 61440")
+						cmdparser.LibcliRegisterParam(&priority, &value)
+						cmdparser.SetParamCmdCode(&value, STP_PRIORITY)
+					}
+				}
+			}
+			{
+				var monitor cmdparser.Param
+				cmdparser.InitParam(&monitor,
+					cmdparser.CMD,
+					"monitor",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Port mirroring")
+				cmdparser.LibcliRegisterParam(&nodeName, &monitor)
+
+				{
+					var session cmdparser.Param
+					cmdparser.InitParam(&session,
+						cmdparser.CMD,
+						"session",
+						nil,
+						nil,
+						cmdparser.INVALID,
+						"",
+						"Copy the frames of source ports out of a destination port")
+					cmdparser.LibcliRegisterParam(&monitor, &session)
+
+					{
+						var id cmdparser.Param
+						cmdparser.InitParam(&id,
+							cmdparser.LEAF,
+							"",
+							nil,
+							validMonitorSession,
+							cmdparser.INT,
+							"session-id",
+							"Id of the session")
+						cmdparser.LibcliRegisterParam(&session, &id)
+
+						{
+							var source cmdparser.Param
+							cmdparser.InitParam(&source,
+								cmdparser.CMD,
+								"source",
+								nil,
+								nil,
+								cmdparser.INVALID,
+								"",
+								"Port whose frames are copied")
+							cmdparser.LibcliRegisterParam(&id, &source)
+
+							{
+								var srcName cmdparser.Param
+								cmdparser.InitParam(&srcName,
+									cmdparser.LEAF,
+									"",
+									nil,
+									nil,
+									cmdparser.STRING,
+									"source-intf",
+									"Name of the source interface")
+								cmdparser.LibcliRegisterParam(&source, &srcName)
+								registerMonitorDest(&srcName)
+
+								{
+									var dir cmdparser.Param
+									cmdparser.InitParam(&dir,
+										cmdparser.LEAF,
+										"",
+										nil,
+										validMonitorDir,
+										cmdparser.STRING,
+										"direction",
+										"Frames copied, rx, tx or both (default)")
+									cmdparser.LibcliRegisterParam(&srcName, &dir)
+									registerMonitorDest(&dir)
+								}
+							}
+						}
+					}
+				}
+			}
+			{
+				var mac cmdparser.Param
+				cmdparser.InitParam(&mac,
+					cmdparser.CMD,
+					"mac",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Static mac entry")
+				cmdparser.LibcliRegisterParam(&nodeName, &mac)
+
+				{
+					var macAddr cmdparser.Param
+					cmdparser.InitParam(&macAddr,
+						cmdparser.LEAF,
+						"",
+						nil,
+						validMacAddr,
+						cmdparser.STRING,
+						"mac-addr",
+						"Mac addr of the host, i.e aa:bb:cc:dd:ee:ff")
+					cmdparser.LibcliRegisterParam(&mac, &macAddr)
+
+					{
+						var vlan cmdparser.Param
+						cmdparser.InitParam(&vlan,
+							cmdparser.CMD,
+							"vlan",
+							nil,
+							nil,
+							cmdparser.INVALID,
+							"",
+							"Vlan of the host")
+						cmdparser.LibcliRegisterParam(&macAddr, &vlan)
+
+						{
+							var vlanId cmdparser.Param
+							cmdparser.InitParam(&vlanId,
+								cmdparser.LEAF,
+								"",
+								nil,
+								validVlan,
+								cmdparser.INT,
+								"vlan-id",
+								"Vlan id")
+							cmdparser.LibcliRegisterParam(&vlan, &vlanId)
+
+							{
+								var intf cmdparser.Param
+								cmdparser.InitParam(&intf,
+									cmdparser.CMD,
+									"intf",
+									nil,
+									nil,
+									cmdparser.INVALID,
+									"",
+									"Port the host is reached through")
+								cmdparser.LibcliRegisterParam(&vlanId, &intf)
+
+								{
+									var intfName cmdparser.Param
+									cmdparser.InitParam(&intfName,
+										cmdparser.LEAF,
+										"",
+										macHandler,
+										nil,
+										cmdparser.STRING,
+										"intf-name",
+										"Interface name")
+									cmdparser.LibcliRegisterParam(&intf, &intfName)
+									cmdparser.SetParamCmdCode(&intfName, MAC_STATIC)
+								}
+							}
+						}
+					}
+				}
+			}
+			{
+				var arp cmdparser.Param
+				cmdparser.InitParam(&arp,
+					cmdparser.CMD,
+					"arp",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Static arp entry")
+				cmdparser.LibcliRegisterParam(&nodeName, &arp)
+
+				{
+					var ipAddr cmdparser.Param
+					cmdparser.InitParam(&ipAddr,
+						cmdparser.LEAF,
+						"",
+						nil,
+						validIPAddr,
+						cmdparser.STRING,
+						"ip-addr",
+						"Ip addr of the neighbor")
+					cmdparser.LibcliRegisterParam(&arp, &ipAddr)
+
+					{
+						var macAddr cmdparser.Param
+						cmdparser.InitParam(&macAddr,
+							cmdparser.LEAF,
+							"",
+							nil,
+							validMacAddr,
+							cmdparser.STRING,
+							"mac-addr",
+							"Mac addr of the neighbor, i.e aa:bb:cc:dd:ee:ff")
+						cmdparser.LibcliRegisterParam(&ipAddr, &macAddr)
+
+						{
+							var intfName cmdparser.Param
+							cmdparser.InitParam(&intfName,
+								cmdparser.LEAF,
+								"",
+								arpHandler,
+								nil,
+								cmdparser.STRING,
+								"intf-name",
+								"Interface the neighbor is reached through")
+							cmdparser.LibcliRegisterParam(&macAddr, &intfName)
+							cmdparser.SetParamCmdCode(&intfName, ARP_STATIC)
+						}
+					}
+				}
+			}
+			{
+				var route cmdparser.Param
+				cmdparser.InitParam(&route,
+					cmdparser.CMD,
+					"route",
+					nil,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Routing Table")
+				cmdparser.LibcliRegisterParam(&nodeName, &route)
+
+				{
+					var dst cmdparser.Param
+					cmdparser.InitParam(&dst,
+						cmdparser.LEAF,
+						"",
+						nil,
+						validIPAddr,
+						cmdparser.STRING,
+						"dst",
+						"Destination Ip Addr")
+					cmdparser.LibcliRegisterParam(&route, &dst)
+
+					{
+						var mask cmdparser.Param
+						cmdparser.InitParam(&mask,
+							cmdparser.LEAF,
+							"",
+							nil,
+							validMask,
+							cmdparser.STRING,
+							"mask",
+							"Mask of Ip Addr")
+						cmdparser.LibcliRegisterParam(&dst, &mask)
+
+						{
+							var gatewayIP cmdparser.Param
+							cmdparser.InitParam(&gatewayIP,
+								cmdparser.LEAF,
+								"",
+								nil,
+								validIPAddr,
+								cmdparser.STRING,
+								"gw-ip",
+								"Gateway IP Addr")
+							cmdparser.LibcliRegisterParam(&mask, &gatewayIP)
+
+							{
+								var outIntf cmdparser.Param
+								cmdparser.InitParam(&outIntf,
+									cmdparser.LEAF,
+									"",
+									l3ConfigHandler,
+									nil,
+									cmdparser.STRING,
+									"out-intf",
+									"Outgoing interface")
+								cmdparser.LibcliRegisterParam(&gatewayIP, &outIntf)
+								cmdparser.SetParamCmdCode(&outIntf, L3_HANDLER)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	{
+		var arp cmdparser.Param
+		cmdparser.InitParam(&arp,
+			cmdparser.CMD,
+			"arp",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Arp settings of all the nodes")
+		cmdparser.LibcliRegisterParam(config, &arp)
+
+		{
+			var timeout cmdparser.Param
+			cmdparser.InitParam(&timeout,
+				cmdparser.CMD,
+				"timeout",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"How long learned entries are kept without being refreshed")
+			cmdparser.LibcliRegisterParam(&arp, &timeout)
+
+			{
+				var secs cmdparser.Param
+				cmdparser.InitParam(&secs,
+					cmdparser.LEAF,
+					"",
+					arpHandler,
+					validCount,
+					cmdparser.INT,
+					"seconds",
+					"Timeout in seconds")
+				cmdparser.LibcliRegisterParam(&timeout, &secs)
+				cmdparser.SetParamCmdCode(&secs, ARP_TIMEOUT)
+			}
+		}
+	}
+	{
+		var mac cmdparser.Param
+		cmdparser.InitParam(&mac,
+			cmdparser.CMD,
+			"mac",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Mac table settings of all the switches")
+		cmdparser.LibcliRegisterParam(config, &mac)
+
+		{
+			var timeout cmdparser.Param
+			cmdparser.InitParam(&timeout,
+				cmdparser.CMD,
+				"timeout",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"How long learned entries are kept without traffic from the host")
+			cmdparser.LibcliRegisterParam(&mac, &timeout)
+
+			{
+				var secs cmdparser.Param
+				cmdparser.InitParam(&secs,
+					cmdparser.LEAF,
+					"",
+					macHandler,
+					validCount,
+					cmdparser.INT,
+					"seconds",
+					"Timeout in seconds")
+				cmdparser.LibcliRegisterParam(&timeout, &secs)
+				cmdparser.SetParamCmdCode(&secs, MAC_TIMEOUT)
+			}
+		}
+	}
+	{
+		var node cmdparser.Param
+		cmdparser.InitParam(&node,
+			cmdparser.CMD,
+			"node",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Given a node name it clears the given table")
+		cmdparser.LibcliRegisterParam(clear, &node)
+
+		{
+			var nodeName cmdparser.Param
+			cmdparser.InitParam(&nodeName,
+				cmdparser.LEAF,
+				"",
+				nil,
+				validNodeName,
+				cmdparser.STRING,
+				"node-name",
+				"Name of a node in the topology")
+			cmdparser.LibcliRegisterParam(&node, &nodeName)
+
+			{
+				var arp cmdparser.Param
+				cmdparser.InitParam(&arp,
+					cmdparser.CMD,
+					"arp",
+					arpHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Forget the learned arp entries")
+				cmdparser.LibcliRegisterParam(&nodeName, &arp)
+				cmdparser.SetParamCmdCode(&arp, ARP_CLEAR)
+
+				{
+					var ipAddr cmdparser.Param
+					cmdparser.InitParam(&ipAddr,
+						cmdparser.LEAF,
+						"",
+						arpHandler,
+						validIPAddr,
+						cmdparser.STRING,
+						"ip-addr",
+						"Forget only the entry of this ip addr")
+					cmdparser.LibcliRegisterParam(&arp, &ipAddr)
+					cmdparser.SetParamCmdCode(&ipAddr, ARP_CLEAR)
+				}
+			}
+			{
+				var mac cmdparser.Param
+				cmdparser.InitParam(&mac,
+					cmdparser.CMD,
+					"mac",
+					macHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Forget the learned mac entries")
+				cmdparser.LibcliRegisterParam(&nodeName, &mac)
+				cmdparser.SetParamCmdCode(&mac, MAC_CLEAR)
+			}
+			{
+				var storm cmdparser.Param
+				cmdparser.InitParam(&storm,
+					cmdparser.CMD,
+					"storm-control",
+					stormHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Reset the storm control counters")
+				cmdparser.LibcliRegisterParam(&nodeName, &storm)
+				cmdparser.SetParamCmdCode(&storm, STORM_CLEAR)
+			}
+			{
+				var portSec cmdparser.Param
+				cmdparser.InitParam(&portSec,
+					cmdparser.CMD,
+					"port-security",
+					portSecHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Reset the port security violation counters")
+				cmdparser.LibcliRegisterParam(&nodeName, &portSec)
+				cmdparser.SetParamCmdCode(&portSec, PORTSEC_CLEAR)
+			}
+			{
+				var neighbors cmdparser.Param
+				cmdparser.InitParam(&neighbors,
+					cmdparser.CMD,
+					"neighbors",
+					lldpHandler,
+					nil,
+					cmdparser.INVALID,
+					"",
+					"Forget the LLDP neighbors, they are discovered again")
+				cmdparser.LibcliRegisterParam(&nodeName, &neighbors)
+				cmdparser.SetParamCmdCode(&neighbors, LLDP_CLEAR)
+			}
+		}
+	}
+}
+
+// registerCaptureCmds adds "capture start file <path>" and "capture stop" under the given param
+func registerCaptureCmds(parent *cmdparser.Param, startCode, stopCode int) {
+	var capture cmdparser.Param
+	cmdparser.InitParam(&capture,
+		cmdparser.CMD,
+		"capture",
+		nil,
+		nil,
+		cmdparser.INVALID,
+		"",
+		"Packet capture in pcapng format")
+	cmdparser.LibcliRegisterParam(parent, &capture)
+
+	{
+		var start cmdparser.Param
+		cmdparser.InitParam(&start,
+			cmdparser.CMD,
+			"start",
+			nil,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Start capturing frames")
+		cmdparser.LibcliRegisterParam(&capture, &start)
+
+		{
+			var file cmdparser.Param
+			cmdparser.InitParam(&file,
+				cmdparser.CMD,
+				"file",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Capture file")
+			cmdparser.LibcliRegisterParam(&start, &file)
+
+			{
+				var path cmdparser.Param
+				cmdparser.InitParam(&path,
+					cmdparser.LEAF,
+					"",
+					captureHandler,
+					nil,
+					cmdparser.STRING,
+					"file",
+					"Path of the pcapng file")
+				cmdparser.LibcliRegisterParam(&file, &path)
+				cmdparser.SetParamCmdCode(&path, startCode)
+			}
+		}
+	}
+	{
+		var stop cmdparser.Param
+		cmdparser.InitParam(&stop,
+			cmdparser.CMD,
+			"stop",
+			captureHandler,
+			nil,
+			cmdparser.INVALID,
+			"",
+			"Stop capturing frames")
+		cmdparser.LibcliRegisterParam(&capture, &stop)
+		cmdparser.SetParamCmdCode(&stop, stopCode)
+	}
+}
+
+// registerMonitorDest adds "destination <dest-intf> [vlan <vlan-id>]" under the given param
+func registerMonitorDest(parent *cmdparser.Param) {
+	var destination cmdparser.Param
+	cmdparser.InitParam(&destination,
+		cmdparser.CMD,
+		"destination",
+		nil,
+		nil,
+		cmdparser.INVALID,
+		"",
+		"Port the copies are sent out of")
+	cmdparser.LibcliRegisterParam(parent, &destination)
+
+	{
+		var dstName cmdparser.Param
+		cmdparser.InitParam(&dstName,
+			cmdparser.LEAF,
+			"",
+			monitorHandler,
+			nil,
+			cmdparser.STRING,
+			"dest-intf",
+			"Name of the destination interface")
+		cmdparser.LibcliRegisterParam(&destination, &dstName)
+		cmdparser.SetParamCmdCode(&dstName, MONITOR_ADD)
+
+		{
+			var vlan cmdparser.Param
+			cmdparser.InitParam(&vlan,
+				cmdparser.CMD,
+				"vlan",
+				nil,
+				nil,
+				cmdparser.INVALID,
+				"",
+				"Only copy the frames of a vlan")
+			cmdparser.LibcliRegisterParam(&dstName, &vlan)
+
+			{
+				var vlanId cmdparser.Param
+				cmdparser.InitParam(&vlanId,
+					cmdparser.LEAF,
+					"",
+					monitorHandler,
+					validVlan,
+					cmdparser.INT,
+					"vlan-id",
+					"Vlan of the frames")
+				cmdparser.LibcliRegisterParam(&vlan, &vlanId)
+				cmdparser.SetParamCmdCode(&vlanId, MONITOR_ADD)
 			}
 		}
 	}
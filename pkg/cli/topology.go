@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools"
+)
+
+// intfLabel describes the addressing of an interface, ip/mask for L3 interfaces
+// and mode plus vlan membership for L2 ones. Interfaces that aren't up are flagged.
+func intfLabel(intf *network.Interface) string {
+	if !network.IsIntfUp(intf) && network.IsIntfLinked(intf) {
+		return strings.TrimSpace(intfAddrLabel(intf) + " (" + network.IntfStateStr(intf) + ")")
+	}
+	return intfAddrLabel(intf)
+}
+
+func intfAddrLabel(intf *network.Interface) string {
+	if network.IsIntfIp(intf) {
+		ip := network.GetIntfIp(intf)
+		return tools.ConvertAddrToStr(ip.Addr[:]) + "/" + strconv.Itoa(int(ip.Mask))
+	}
+
+	mode := network.GetIntfL2Mode(intf)
+	if mode != network.ACCESS && mode != network.TRUNK {
+		return ""
+	}
+	var vlans []string
+	for _, val := range network.GetIntfVlanMembership(intf) {
+		if val == 0 {
+			break
+		}
+		vlans = append(vlans, strconv.Itoa(int(val)))
+	}
+	if len(vlans) == 0 {
+		return string(mode)
+	}
+	return string(mode) + " vlan " + strings.Join(vlans, ",")
+}
+
+func nodeLabel(node *network.Node) string {
+	if network.IsNodeIp(node) {
+		return node.Name + "\n" + tools.ConvertAddrToStr(network.GetNodeIp(node).Addr[:])
+	}
+	return node.Name
+}
+
+func dumpGraphDot(graph *network.Graph) {
+	fmt.Printf("graph %s {\n", strconv.Quote(graph.Name))
+	fmt.Println("\tnode [shape=box];")
+	for node := graph.List; node != nil; node = node.Next {
+		fmt.Printf("\t%s [label=%s];\n", strconv.Quote(node.Name), strconv.Quote(nodeLabel(node)))
+	}
+
+	// every link is seen from both of its ends, only emit it from the first one
+	seen := map[string]bool{}
+	for node := graph.List; node != nil; node = node.Next {
+		for _, intf := range node.Intf {
+			if intf == nil {
+				continue
+			}
+			nbrNode, err := network.GetNbrNode(intf)
+			if err != nil {
+				continue
+			}
+			nbrIntf, _ := network.GetIntfByIntfName(nbrNode, network.GetNbrIntf(intf))
+			if nbrIntf == nil || seen[nbrNode.Name+":"+nbrIntf.Name] {
+				continue
+			}
+			seen[node.Name+":"+intf.Name] = true
+
+			fmt.Printf("\t%s -- %s [label=%s, taillabel=%s, headlabel=%s];\n",
+				strconv.Quote(node.Name),
+				strconv.Quote(nbrNode.Name),
+				strconv.Quote(strings.TrimSpace("cost "+strconv.Itoa(int(network.GetLinkCost(intf)))+"\n"+network.ImpairmentStr(network.GetLinkImpairment(intf)))),
+				strconv.Quote(strings.TrimSpace(intf.Name+"\n"+intfLabel(intf))),
+				strconv.Quote(strings.TrimSpace(nbrIntf.Name+"\n"+intfLabel(nbrIntf))))
+		}
+	}
+	fmt.Println("}")
+}
+
+func dumpGraphAscii(graph *network.Graph) {
+	fmt.Println("Name: " + Cyan + graph.Name + Reset)
+	for node := graph.List; node != nil; node = node.Next {
+		lines := strings.Split(nodeLabel(node), "\n")
+		width := 0
+		for _, line := range lines {
+			width = max(width, len(line))
+		}
+
+		fmt.Println()
+		fmt.Println("+" + strings.Repeat("-", width+2) + "+")
+		for _, line := range lines {
+			fmt.Printf("| %-*s |\n", width, line)
+		}
+		fmt.Println("+" + strings.Repeat("-", width+2) + "+")
+
+		var local, remote, unlinked []string
+		var nbrs, links []string
+		for _, intf := range node.Intf {
+			if intf == nil {
+				continue
+			}
+			nbrNode, err := network.GetNbrNode(intf)
+			if err != nil {
+				unlinked = append(unlinked, strings.TrimSpace(intf.Name+" "+intfLabel(intf)))
+				continue
+			}
+			nbrIntf, _ := network.GetIntfByIntfName(nbrNode, network.GetNbrIntf(intf))
+			if nbrIntf == nil {
+				continue
+			}
+			local = append(local, strings.TrimSpace(intf.Name+" "+intfLabel(intf)))
+			remote = append(remote, strings.TrimSpace(intfLabel(nbrIntf)+" "+nbrIntf.Name))
+			nbrs = append(nbrs, nbrNode.Name)
+			links = append(links, strings.TrimSuffix("cost "+strconv.Itoa(int(network.GetLinkCost(intf)))+", "+network.ImpairmentStr(network.GetLinkImpairment(intf)), ", "))
+		}
+
+		localWidth, remoteWidth := 0, 0
+		for i := range local {
+			localWidth = max(localWidth, len(local[i]))
+			remoteWidth = max(remoteWidth, len(remote[i]))
+		}
+		for i := range local {
+			fmt.Printf("  %-*s ----[%s]---- %*s [%s]\n", localWidth, local[i], links[i], remoteWidth, remote[i], Cyan+nbrs[i]+Reset)
+		}
+		for _, name := range unlinked {
+			fmt.Println("  " + name + " (not connected)")
+		}
+	}
+}
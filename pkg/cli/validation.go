@@ -3,6 +3,9 @@ package cli
 import (
 	"strconv"
 	"strings"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/pkg/stack"
 )
 
 func validNodeName(str string) bool {
@@ -40,3 +43,140 @@ func validMask(str string) bool {
 
     return false
 }
+
+func validCost(str string) bool {
+	if cost, err := strconv.Atoi(str); err == nil {
+		return cost >= 0
+	}
+	return false
+}
+
+func validL2Mode(str string) bool {
+	return str == string(network.ACCESS) || str == string(network.TRUNK)
+}
+
+func validVlan(str string) bool {
+	if vlan, err := strconv.Atoi(str); err == nil {
+		return vlan > 0 && vlan < 4095
+	}
+	return false
+}
+
+func validMs(str string) bool {
+	if ms, err := strconv.ParseFloat(str, 64); err == nil {
+		return ms >= 0
+	}
+	return false
+}
+
+func validPercent(str string) bool {
+	if pct, err := strconv.ParseFloat(str, 64); err == nil {
+		return pct >= 0 && pct <= 100
+	}
+	return false
+}
+
+func validJitterDist(str string) bool {
+	return str == string(network.UNIFORM) || str == string(network.NORMAL)
+}
+
+func validCount(str string) bool {
+	if count, err := strconv.Atoi(str); err == nil {
+		return count > 0
+	}
+	return false
+}
+
+func validPingSize(str string) bool {
+	if size, err := strconv.Atoi(str); err == nil {
+		return size >= 0 && size <= stack.PING_MAX_SIZE
+	}
+	return false
+}
+
+func validTtl(str string) bool {
+	if ttl, err := strconv.Atoi(str); err == nil {
+		return ttl > 0 && ttl <= 255
+	}
+	return false
+}
+
+func validMacAddr(str string) bool {
+	addr := strings.Split(str, ":")
+	if len(addr) != 6 {
+		return false
+	}
+	for _, val := range addr {
+		if _, err := strconv.ParseUint(val, 16, 8); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func validStpPriority(str string) bool {
+	if priority, err := strconv.Atoi(str); err == nil {
+		return priority >= 0 && priority <= stack.STP_MAX_PRIORITY && priority%stack.STP_PRIORITY_STEP == 0
+	}
+	return false
+}
+
+func validChannelId(str string) bool {
+	if id, err := strconv.Atoi(str); err == nil {
+		return id > 0 && id <= 0xffff
+	}
+	return false
+}
+
+func validChannelMode(str string) bool {
+	mode := network.ChannelMode(str)
+	return mode == network.CHANNEL_ON || mode == network.CHANNEL_ACTIVE || mode == network.CHANNEL_PASSIVE
+}
+
+func validStormType(str string) bool {
+	return network.IsValidStormType(network.StormType(str))
+}
+
+func validStormAction(str string) bool {
+	action := network.StormAction(str)
+	return action == network.STORM_DROP || action == network.STORM_SHUTDOWN
+}
+
+// validPps accepts a level of 0, every frame of the type is dropped then
+func validPps(str string) bool {
+	_, err := strconv.ParseUint(str, 10, 32)
+	return err == nil
+}
+
+func validPortSecMaximum(str string) bool {
+	if maximum, err := strconv.Atoi(str); err == nil {
+		return maximum >= 1 && maximum <= network.PORTSEC_MAX_MAXIMUM
+	}
+	return false
+}
+
+func validPortSecViolation(str string) bool {
+	return network.IsValidPortSecViolation(network.PortSecViolation(str))
+}
+
+func validMonitorSession(str string) bool {
+	if id, err := strconv.Atoi(str); err == nil {
+		return id >= 1 && id <= network.MONITOR_MAX_SESSION
+	}
+	return false
+}
+
+func validMonitorDir(str string) bool {
+	return network.IsValidMonitorDir(network.MonitorDir(str))
+}
+
+func validOnOff(str string) bool {
+	return str == "on" || str == "off"
+}
+
+func validMtu(str string) bool {
+	if mtu, err := strconv.Atoi(str); err == nil {
+		return mtu >= network.INTF_MIN_MTU && mtu <= network.INTF_MAX_MTU
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A port-channel bundles parallel links into a single L2 port. The members only carry the
+// frames of the bundle, the L2 mode and vlans that apply are those of the port-channel.
+
+type ChannelMode string
+
+const (
+	CHANNEL_ON      ChannelMode = "on"      // static bundle, members are used as soon as they are up
+	CHANNEL_ACTIVE  ChannelMode = "active"  // LACP, starts the negotiation
+	CHANNEL_PASSIVE ChannelMode = "passive" // LACP, only answers an active partner
+)
+
+func ChannelName(id uint16) string {
+	return fmt.Sprintf("po%d", id)
+}
+
+// ParseChannelName returns the id of a port-channel name of the form po<id>
+func ParseChannelName(name string) (uint16, bool) {
+	if !strings.HasPrefix(name, "po") {
+		return 0, false
+	}
+	num, err := strconv.Atoi(strings.TrimPrefix(name, "po"))
+	if err != nil || num < 1 || num > 0xffff || ChannelName(uint16(num)) != name {
+		return 0, false
+	}
+	return uint16(num), true
+}
+
+func IsIntfChannel(intf *Interface) bool {
+	return intf.prop.channelId != 0
+}
+
+func GetIntfChannelId(intf *Interface) uint16 {
+	return intf.prop.channelId
+}
+
+// GetIntfChannel returns the port-channel the interface is a member of, nil if it isn't bundled
+func GetIntfChannel(intf *Interface) *Interface {
+	return intf.prop.channel
+}
+
+func GetIntfChannelMode(intf *Interface) ChannelMode {
+	return intf.prop.channelMode
+}
+
+// IsIntfBundled tells whether the member currently carries the frames of its port-channel
+func IsIntfBundled(intf *Interface) bool {
+	return intf.prop.bundled
+}
+
+func SetIntfBundled(intf *Interface, bundled bool) {
+	if intf.prop.channel == nil || intf.prop.bundled == bundled {
+		return
+	}
+	trackLinkState(func() {
+		intf.prop.bundled = bundled
+	}, intf.prop.channel)
+}
+
+func GetChannelMembers(channel *Interface) []*Interface {
+	var members []*Interface
+	if channel.Att_node == nil {
+		return members
+	}
+	for _, intf := range channel.Att_node.Intf {
+		if intf == nil {
+			break
+		}
+		if intf.prop.channel == channel {
+			members = append(members, intf)
+		}
+	}
+	return members
+}
+
+// NodeSetIntfChannelGroup makes the interface a member of port-channel id, the port-channel is
+// created on its first member and takes over the L2 mode and vlans of that member
+func NodeSetIntfChannelGroup(node *Node, name string, id uint16, mode ChannelMode) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if IsIntfIp(intf) || IsIntfSvi(intf) || IsIntfChannel(intf) {
+		return fmt.Errorf("Interface: %s can't be part of a port-channel", node.Name+":"+name)
+	}
+	if id == 0 {
+		return fmt.Errorf("Invalid port-channel id: %d", id)
+	}
+	if mode != CHANNEL_ON && mode != CHANNEL_ACTIVE && mode != CHANNEL_PASSIVE {
+		return fmt.Errorf("Unknown port-channel mode: %s", mode)
+	}
+
+	channel, err := GetIntfByIntfName(node, ChannelName(id))
+	if err != nil {
+		i, err := getNodeIntfAvailableSlot(node)
+		if err != nil {
+			return fmt.Errorf("Node available slots in node: %s", node.Name)
+		}
+		channel = &Interface{Name: ChannelName(id), Att_node: node}
+		channel.prop.channelId = id
+		channel.prop.l2Mode = intf.prop.l2Mode
+		channel.prop.vlan = intf.prop.vlan
+		channel.prop.nativeVlan = intf.prop.nativeVlan
+		node.Intf[i] = channel
+	} else if !IsIntfChannel(channel) {
+		return fmt.Errorf("Interface: %s is not a port-channel", node.Name+":"+channel.Name)
+	}
+
+	SetIntfBundled(intf, false)
+	intf.prop.channel = channel
+	intf.prop.channelMode = mode
+	return nil
+}
+
+func NodeUnsetIntfChannelGroup(node *Node, name string) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.prop.channel == nil {
+		return fmt.Errorf("Interface: %s is not part of a port-channel", node.Name+":"+name)
+	}
+
+	SetIntfBundled(intf, false)
+	intf.prop.channel = nil
+	intf.prop.channelMode = ""
+	return nil
+}
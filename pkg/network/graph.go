@@ -8,9 +8,10 @@ const MAX_INTF_SIZE int = 10
 const MAX_INTF_PER_NODE int = 10
 
 type link struct {
-	intf1 Interface
-	intf2 Interface
-	cost  uint
+	intf1  *Interface
+	intf2  *Interface
+	cost   uint
+	impair Impairment
 }
 
 type Interface struct {
@@ -55,7 +56,10 @@ func GetIntfByIntfName(node *Node, name string) (*Interface, error) {
 }
 
 func GetNbrIntf(intf *Interface) string {
-	if intf.conn.intf1.Name == intf.Name {
+	if intf.conn == nil {
+		return ""
+	}
+	if intf.conn.intf1 == intf {
 		return intf.conn.intf2.Name
 	}
 	return intf.conn.intf1.Name
@@ -66,19 +70,30 @@ func GetNbrNode(intf *Interface) (*Node, error) {
 		return nil, fmt.Errorf("Either att_node or wire is not there")
 	}
 
-	if intf.conn.intf1 == *intf {
+	if intf.conn.intf1 == intf {
 		return intf.conn.intf2.Att_node, nil
 	}
 	return intf.conn.intf1.Att_node, nil
 }
 
+func IsIntfLinked(intf *Interface) bool {
+	return intf.conn != nil
+}
+
+func GetLinkCost(intf *Interface) uint {
+	if intf.conn == nil {
+		return 0
+	}
+	return intf.conn.cost
+}
+
 func GetNodeByNodeName(graph *Graph, name string) (*Node, error) {
 	for node := graph.List; node != nil; node = node.Next {
 		if node.Name == name {
 			return node, nil
 		}
 	}
-	return nil, fmt.Errorf("No interface with the given name: %s", name)
+	return nil, fmt.Errorf("No node with the given name: %s", name)
 }
 
 func CreateNewGraph(name string) *Graph {
@@ -88,6 +103,7 @@ func CreateNewGraph(name string) *Graph {
 
 func CreateGraphNode(graph *Graph, name string) *Node {
 	node := Node{Name: name}
+	nodeAssignSystemMac(&node)
 
 	if graph.List == nil {
 		graph.List = &node
@@ -103,32 +119,128 @@ func CreateGraphNode(graph *Graph, name string) *Node {
 	return &node
 }
 
+// getOrCreateIntf returns the interface with the given name if it exists and isn't wired to anything,
+// otherwise a new interface is created in the first free slot of the node.
+func getOrCreateIntf(node *Node, name string) (*Interface, error) {
+	if intf, err := GetIntfByIntfName(node, name); err == nil {
+		if IsIntfSvi(intf) || IsIntfChannel(intf) {
+			return nil, fmt.Errorf("Interface: %s is a virtual interface, it can't be wired", node.Name+":"+name)
+		}
+		if intf.conn != nil {
+			return nil, fmt.Errorf("Interface: %s is already connected", node.Name+":"+name)
+		}
+		return intf, nil
+	}
+
+	i, err := getNodeIntfAvailableSlot(node)
+	if err != nil {
+		return nil, fmt.Errorf("Node available slots in node: %s", node.Name)
+	}
+	intf := &Interface{Name: name, Att_node: node}
+	intf.prop.l2Mode = UNKNOWN
+	node.Intf[i] = intf
+
+	return intf, nil
+}
+
 func InsertLinkBetweenNodes(node1, node2 *Node, fromIntfNode, toIntfNode string, cost uint) error {
-	intf1 := Interface{Name: fromIntfNode}
-	intf2 := Interface{Name: toIntfNode}
-	intf1.prop.l2Mode = UNKNOWN
-	intf2.prop.l2Mode = UNKNOWN
+	if node1 == node2 && fromIntfNode == toIntfNode {
+		return fmt.Errorf("Can't connect interface: %s to itself", node1.Name+":"+fromIntfNode)
+	}
+	if intf, err := GetIntfByIntfName(node2, toIntfNode); err == nil && intf.conn != nil {
+		return fmt.Errorf("Interface: %s is already connected", node2.Name+":"+toIntfNode)
+	}
+
+	intf1, err := getOrCreateIntf(node1, fromIntfNode)
+	if err != nil {
+		return err
+	}
+	intf2, err := getOrCreateIntf(node2, toIntfNode)
+	if err != nil {
+		return err
+	}
 
-	wire := link{intf1: intf1, intf2: intf2}
+	wire := link{intf1: intf1, intf2: intf2, cost: cost}
 
 	// Setting back pointers
-	wire.intf1.conn = &wire
-	wire.intf2.conn = &wire
+	trackLinkState(func() {
+		intf1.conn = &wire
+		intf2.conn = &wire
+	}, intf1, intf2)
 
-	wire.intf1.Att_node = node1
-	wire.intf2.Att_node = node2
-	wire.cost = cost
+	return nil
+}
 
-	if i, err := getNodeIntfAvailableSlot(node1); err == nil {
-		node1.Intf[i] = &wire.intf1
-	} else {
-		return fmt.Errorf("Node available slots in node1")
+// RemoveLink unwires the link attached to the given interface, both ends stay on their nodes
+func RemoveLink(node *Node, name string) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.conn == nil {
+		return fmt.Errorf("Interface: %s is not connected", node.Name+":"+name)
 	}
-	if i, err := getNodeIntfAvailableSlot(node2); err == nil {
-		node2.Intf[i] = &wire.intf2
+
+	wire := intf.conn
+	trackLinkState(func() {
+		wire.intf1.conn = nil
+		wire.intf2.conn = nil
+	}, wire.intf1, wire.intf2)
+
+	return nil
+}
+
+// RemoveInterface unwires and deletes the interface from the node. The Intf array is
+// kept packed, since lookups stop at the first empty slot.
+func RemoveInterface(node *Node, name string) error {
+	for i := 0; i < MAX_INTF_PER_NODE; i++ {
+		if node.Intf[i] == nil {
+			break
+		}
+		if node.Intf[i].Name != name {
+			continue
+		}
+
+		if node.Intf[i].conn != nil {
+			RemoveLink(node, name)
+		}
+		for _, member := range GetChannelMembers(node.Intf[i]) {
+			NodeUnsetIntfChannelGroup(node, member.Name)
+		}
+		node.Intf[i].Att_node = nil
+		copy(node.Intf[i:], node.Intf[i+1:])
+		node.Intf[len(node.Intf)-1] = nil
+		return nil
+	}
+	return fmt.Errorf("No interface with the given name: %s", name)
+}
+
+func RemoveGraphNode(graph *Graph, node *Node) error {
+	found := false
+	for curr := graph.List; curr != nil; curr = curr.Next {
+		if curr == node {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Node: %s is not part of graph: %s", node.Name, graph.Name)
+	}
+
+	for node.Intf[0] != nil {
+		RemoveInterface(node, node.Intf[0].Name)
+	}
+
+	if node.Prev != nil {
+		node.Prev.Next = node.Next
 	} else {
-		return fmt.Errorf("Node available slots in node2")
+		graph.List = node.Next
+	}
+	if node.Next != nil {
+		node.Next.Prev = node.Prev
 	}
+	node.Prev = nil
+	node.Next = nil
 
 	return nil
 }
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type JitterDist string
+
+const (
+	UNIFORM JitterDist = "uniform"
+	NORMAL  JitterDist = "normal"
+)
+
+// Impairment describes how a link degrades the frames crossing it, it applies to both directions
+type Impairment struct {
+	Delay      time.Duration
+	Jitter     time.Duration
+	JitterDist JitterDist
+	Loss       float64 // percentage of frames dropped
+	Duplicate  float64 // percentage of frames delivered twice
+	Reorder    float64 // percentage of frames held back so that later frames overtake them
+	Bandwidth  uint64  // bits per second, 0 means unlimited
+}
+
+func GetLinkImpairment(intf *Interface) *Impairment {
+	if intf.conn == nil {
+		return nil
+	}
+	return &intf.conn.impair
+}
+
+func SetLinkImpairment(node *Node, name string, impair Impairment) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.conn == nil {
+		return fmt.Errorf("Interface: %s is not connected", node.Name+":"+name)
+	}
+	if err := validImpairment(&impair); err != nil {
+		return err
+	}
+
+	intf.conn.impair = impair
+	return nil
+}
+
+func validImpairment(impair *Impairment) error {
+	if impair.Delay < 0 || impair.Jitter < 0 {
+		return fmt.Errorf("Delay and jitter can't be negative")
+	}
+	if impair.JitterDist == "" {
+		impair.JitterDist = UNIFORM
+	}
+	if impair.JitterDist != UNIFORM && impair.JitterDist != NORMAL {
+		return fmt.Errorf("Unknown jitter distribution: %s", impair.JitterDist)
+	}
+	for _, pct := range []float64{impair.Loss, impair.Duplicate, impair.Reorder} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("Percentages have to be between 0 and 100")
+		}
+	}
+	return nil
+}
+
+func IsLinkImpaired(impair *Impairment) bool {
+	return impair != nil && *impair != Impairment{JitterDist: impair.JitterDist}
+}
+
+func ImpairmentStr(impair *Impairment) string {
+	if !IsLinkImpaired(impair) {
+		return ""
+	}
+
+	var parts []string
+	if impair.Delay > 0 {
+		parts = append(parts, "delay "+impair.Delay.String())
+	}
+	if impair.Jitter > 0 {
+		parts = append(parts, fmt.Sprintf("jitter %v %s", impair.Jitter, impair.JitterDist))
+	}
+	if impair.Loss > 0 {
+		parts = append(parts, fmt.Sprintf("loss %g%%", impair.Loss))
+	}
+	if impair.Duplicate > 0 {
+		parts = append(parts, fmt.Sprintf("duplicate %g%%", impair.Duplicate))
+	}
+	if impair.Reorder > 0 {
+		parts = append(parts, fmt.Sprintf("reorder %g%%", impair.Reorder))
+	}
+	if impair.Bandwidth > 0 {
+		parts = append(parts, fmt.Sprintf("bandwidth %dkbps", impair.Bandwidth/1000))
+	}
+	return strings.Join(parts, ", ")
+}
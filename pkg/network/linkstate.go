@@ -0,0 +1,100 @@
+package network
+
+// LinkStateHandler is called whenever the operational state of an interface changes
+type LinkStateHandler func(intf *Interface, up bool)
+
+var linkStateHandlers []LinkStateHandler
+
+func SubscribeLinkState(fn LinkStateHandler) {
+	linkStateHandlers = append(linkStateHandlers, fn)
+}
+
+func IsIntfAdminUp(intf *Interface) bool {
+	return !intf.prop.isShutdown
+}
+
+// IsIntfUp reports the operational state, an interface is up when both ends of its link are admin up
+func IsIntfUp(intf *Interface) bool {
+	if IsIntfSvi(intf) {
+		// nothing is wired to a vlan interface
+		return IsIntfAdminUp(intf)
+	}
+	if IsIntfChannel(intf) {
+		if !IsIntfAdminUp(intf) {
+			return false
+		}
+		for _, member := range GetChannelMembers(intf) {
+			if IsIntfBundled(member) && IsIntfUp(member) {
+				return true
+			}
+		}
+		return false
+	}
+	if !IsIntfAdminUp(intf) || intf.conn == nil {
+		return false
+	}
+	if intf.conn.intf1 == intf {
+		return IsIntfAdminUp(intf.conn.intf2)
+	}
+	return IsIntfAdminUp(intf.conn.intf1)
+}
+
+func NodeSetIntfShutdown(node *Node, name string, shutdown bool) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	trackLinkState(func() {
+		intf.prop.isShutdown = shutdown
+		intf.prop.errDisabled = false
+	}, linkEnds(intf)...)
+
+	return nil
+}
+
+// linkEnds returns the interface along with its neighbor, if it is wired to one,
+// and the port-channels they are members of
+func linkEnds(intf *Interface) []*Interface {
+	ends := []*Interface{intf}
+	if intf.conn != nil {
+		ends = []*Interface{intf.conn.intf1, intf.conn.intf2}
+	}
+	for _, end := range ends {
+		if end.prop.channel != nil {
+			ends = append(ends, end.prop.channel)
+		}
+	}
+	return ends
+}
+
+// trackLinkState runs fn and notifies the subscribers of every given interface whose state it changed
+func trackLinkState(fn func(), intfs ...*Interface) {
+	before := make([]bool, len(intfs))
+	for i, intf := range intfs {
+		before[i] = IsIntfUp(intf)
+	}
+
+	fn()
+
+	for i, intf := range intfs {
+		if up := IsIntfUp(intf); up != before[i] {
+			for _, handler := range linkStateHandlers {
+				handler(intf, up)
+			}
+		}
+	}
+}
+
+func IntfStateStr(intf *Interface) string {
+	if IsIntfErrDisabled(intf) {
+		return "err-disabled"
+	}
+	if !IsIntfAdminUp(intf) {
+		return "admin down"
+	}
+	if !IsIntfUp(intf) {
+		return "down"
+	}
+	return "up"
+}
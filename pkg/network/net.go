@@ -4,10 +4,17 @@ import (
 	"fmt"
 	"github.com/gkarthikreddi/tcp/tools"
 	"net"
+	"time"
 )
 
 const (
 	MAX_VLAN_MEMBERSHIP = 10
+	VLAN_MIN_ID         = 1
+	VLAN_MAX_ID         = 4094 // 0 and 4095 are reserved by 802.1Q
+
+	INTF_DEF_MTU = 1500
+	INTF_MIN_MTU = 68 // smallest mtu an IPv4 link may have (RFC 791)
+	INTF_MAX_MTU = 9216
 )
 
 type Ip struct {
@@ -27,6 +34,14 @@ const (
 	UNKNOWN L2Mode = "unknown"
 )
 
+// ArpState tells whether the mac addr of an arp entry is known yet
+type ArpState string
+
+const (
+	ARP_COMPLETE   ArpState = "complete"
+	ARP_INCOMPLETE ArpState = "incomplete"
+)
+
 type nodeProp struct {
 	// L3 properties
 	isLbAddr bool
@@ -36,32 +51,59 @@ type nodeProp struct {
 	// L2 properties
 	arpTable *ArpEntry
 	macTable *MacEntry
+	stp      *StpBridge
+	sysMac   Mac // identifies the node as a bridge, a lacp system and an lldp chassis
+
+	monitor []*MonitorSession
 
 	port   int
 	socket *net.UDPAddr
+	conn   *net.UDPConn
 }
 
 type intfProp struct {
-	macAddr  Mac
-	isIpAddr bool
-	ipAddr   Ip
+	macAddr    Mac
+	isIpAddr   bool
+	ipAddr     Ip
+	isShutdown bool
+	proxyArp   bool // answer arp requests for addrs routed through another interface
+	mtu        int  // largest ethernet payload sent or received, 0 for INTF_DEF_MTU
 
 	// L2 properties
-	l2Mode L2Mode
-	vlan   [MAX_VLAN_MEMBERSHIP]uint16
+	l2Mode     L2Mode
+	vlan       [MAX_VLAN_MEMBERSHIP]uint16
+	nativeVlan uint16 // vlan of the untagged frames of a trunk, 0 when they are dropped
+
+	sviVlan uint16 // vlan a switched virtual interface routes for, 0 for physical interfaces
+
+	// Link aggregation
+	channelId   uint16     // non zero for a port-channel
+	channel     *Interface // port-channel of a member
+	channelMode ChannelMode
+	bundled     bool
+
+	storm       *StormControl
+	portSec     *PortSecurity
+	errDisabled bool // shut down after a violation rather than by configuration
 }
 
 type ArpEntry struct {
-	IpAddr  *Ip
-	MacAddr *Mac
-	Name    string
+	IpAddr   *Ip
+	MacAddr  *Mac
+	Name     string
+	State    ArpState
+	IsStatic bool      // configured entries never age out
+	Updated  time.Time // last time the entry was learned or used
 	Next    *ArpEntry
 	Prev    *ArpEntry
 }
 
 type MacEntry struct {
-	MacAddr *Mac
-	Name    string
+	Vlan     uint16 // 0 for frames switched without a vlan
+	MacAddr  *Mac
+	Name     string
+	IsStatic bool      // configured entries never age out
+	Updated  time.Time // last time a frame was received from the mac addr
 	Next    *MacEntry
 	Prev    *MacEntry
 }
@@ -92,6 +134,10 @@ func GetIntfMac(intf *Interface) *Mac {
 	return &intf.prop.macAddr
 }
 
+func IsIntfProxyArp(intf *Interface) bool {
+	return intf.prop.proxyArp
+}
+
 func GetIntfL2Mode(intf *Interface) L2Mode {
 	return intf.prop.l2Mode
 }
@@ -100,10 +146,51 @@ func GetIntfVlanMembership(intf *Interface) []uint16 {
 	return intf.prop.vlan[:]
 }
 
+func GetIntfNativeVlan(intf *Interface) uint16 {
+	return intf.prop.nativeVlan
+}
+
+// IsIntfVlanMember tells whether the L2 interface switches frames of the vlan, a trunk always carries its native vlan
+func IsIntfVlanMember(intf *Interface, vlan uint16) bool {
+	switch intf.prop.l2Mode {
+	case ACCESS:
+		return intf.prop.vlan[0] == vlan
+	case TRUNK:
+		if vlan == intf.prop.nativeVlan {
+			return true
+		}
+		for _, val := range intf.prop.vlan {
+			if val == 0 {
+				break
+			}
+			if val == vlan {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func IsIntfSvi(intf *Interface) bool {
+	return intf.prop.sviVlan != 0
+}
+
+func GetIntfSviVlan(intf *Interface) uint16 {
+	return intf.prop.sviVlan
+}
+
+func IsValidVlan(vlan uint16) bool {
+	return vlan >= VLAN_MIN_ID && vlan <= VLAN_MAX_ID
+}
+
 func GetNodeIp(node *Node) *Ip {
 	return &node.prop.lbAddr
 }
 
+func GetNodeSystemMac(node *Node) *Mac {
+	return &node.prop.sysMac
+}
+
 func GetNodePort(node *Node) int {
 	return node.prop.port
 }
@@ -112,6 +199,10 @@ func GetNodeSocket(node *Node) *net.UDPAddr {
 	return node.prop.socket
 }
 
+func GetNodeConn(node *Node) *net.UDPConn {
+	return node.prop.conn
+}
+
 func GetNodeArpTable(node *Node) *ArpEntry {
 	return node.prop.arpTable
 }
@@ -132,6 +223,10 @@ func AssignNodeSocket(node *Node, socket *net.UDPAddr) {
 	node.prop.socket = socket
 }
 
+func AssignNodeConn(node *Node, conn *net.UDPConn) {
+	node.prop.conn = conn
+}
+
 func AssignNodeArpTable(node *Node, arpEntry *ArpEntry) {
 	node.prop.arpTable = arpEntry
 }
@@ -159,7 +254,7 @@ func NodeSetLbAddr(node *Node, addr string) bool {
 
 func NodeSetIntfIpAddr(node *Node, name, addr string, mask uint8) bool {
 	intf, err := GetIntfByIntfName(node, name)
-	if err != nil {
+	if err != nil || IsIntfChannel(intf) || intf.prop.channel != nil {
 		return false
 	}
 
@@ -173,13 +268,61 @@ func NodeSetIntfIpAddr(node *Node, name, addr string, mask uint8) bool {
 	return true
 }
 
+func NodeSetIntfMacAddr(node *Node, name string, mac [6]byte) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	intf.prop.macAddr.Addr = mac
+	return nil
+}
+
+func NodeSetIntfProxyArp(node *Node, name string, enable bool) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if enable && !IsIntfIp(intf) {
+		return fmt.Errorf("Interface: %s has no ip addr", node.Name+":"+name)
+	}
+
+	intf.prop.proxyArp = enable
+	return nil
+}
+
+func GetIntfMtu(intf *Interface) int {
+	if intf.prop.mtu == 0 {
+		return INTF_DEF_MTU
+	}
+	return intf.prop.mtu
+}
+
+func NodeSetIntfMtu(node *Node, name string, mtu int) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if mtu < INTF_MIN_MTU || mtu > INTF_MAX_MTU {
+		return fmt.Errorf("Invalid mtu: %d, it goes from %d to %d", mtu, INTF_MIN_MTU, INTF_MAX_MTU)
+	}
+
+	intf.prop.mtu = mtu
+	return nil
+}
+
 func NodeUnsetIntfIpAddr(node *Node, name string) bool {
 	intf, err := GetIntfByIntfName(node, name)
 	if err != nil {
 		return false
 	}
 
-	intf.prop = intfProp{}
+	intf.prop = intfProp{isShutdown: intf.prop.isShutdown,
+		mtu:         intf.prop.mtu,
+		sviVlan:     intf.prop.sviVlan,
+		channelId:   intf.prop.channelId,
+		channel:     intf.prop.channel,
+		channelMode: intf.prop.channelMode}
 
 	return true
 }
@@ -209,7 +352,7 @@ func NodeGetMatchingSubnet(node *Node, ip *Ip) (*Interface, error) {
 
 func NodeSetIntfL2Mode(node *Node, name string, mode L2Mode) bool {
 	intf, err := GetIntfByIntfName(node, name)
-	if err != nil {
+	if err != nil || IsIntfSvi(intf) {
 		return false
 	}
 
@@ -220,6 +363,10 @@ func NodeSetIntfL2Mode(node *Node, name string, mode L2Mode) bool {
 		intf.prop.l2Mode = mode
 	}
 
+	// the native vlan belongs to the trunk, it doesn't come back if the port turns into one again
+	if mode != TRUNK {
+		intf.prop.nativeVlan = 0
+	}
 	intf.prop.l2Mode = mode
 	return true
 }
@@ -233,6 +380,9 @@ func NodeSetIntfVlanMembership(node *Node, name string, vlan uint16) error {
 	if IsIntfIp(intf) {
 		return fmt.Errorf("Interface: %s configured with L3 Mode, can't assign vlan membership", node.Name+":"+intf.Name)
 	}
+	if !IsValidVlan(vlan) {
+		return fmt.Errorf("Invalid vlan: %d, vlans go from %d to %d", vlan, VLAN_MIN_ID, VLAN_MAX_ID)
+	}
 
 	if intf.prop.l2Mode == ACCESS {
 		intf.prop.vlan[0] = vlan
@@ -252,6 +402,36 @@ func NodeSetIntfVlanMembership(node *Node, name string, vlan uint16) error {
 	return fmt.Errorf("L2 Mode is not set on interface: %s", node.Name+":"+intf.Name)
 }
 
+func NodeSetIntfNativeVlan(node *Node, name string, vlan uint16) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if IsIntfIp(intf) || intf.prop.l2Mode != TRUNK {
+		return fmt.Errorf("Interface: %s is not a trunk", node.Name+":"+intf.Name)
+	}
+	if !IsValidVlan(vlan) {
+		return fmt.Errorf("Invalid vlan: %d, vlans go from %d to %d", vlan, VLAN_MIN_ID, VLAN_MAX_ID)
+	}
+
+	intf.prop.nativeVlan = vlan
+	return nil
+}
+
+// NodeUnsetIntfNativeVlan makes the trunk drop untagged frames again
+func NodeUnsetIntfNativeVlan(node *Node, name string) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if IsIntfIp(intf) || intf.prop.l2Mode != TRUNK {
+		return fmt.Errorf("Interface: %s is not a trunk", node.Name+":"+intf.Name)
+	}
+
+	intf.prop.nativeVlan = 0
+	return nil
+}
+
 func intfAssignMacAddr(intf *Interface) error {
 	if mac, err := tools.RandomMacAddr(); err == nil {
 		for i, val := range mac {
@@ -263,6 +443,17 @@ func intfAssignMacAddr(intf *Interface) error {
 	}
 }
 
+// nodeAssignSystemMac gives the node the unicast, locally administered mac addr of its L2 protocols
+func nodeAssignSystemMac(node *Node) error {
+	mac, err := tools.RandomMacAddr()
+	if err != nil {
+		return err
+	}
+	copy(node.prop.sysMac.Addr[:], mac)
+	node.prop.sysMac.Addr[0] = node.prop.sysMac.Addr[0]&^0x01 | 0x02
+	return nil
+}
+
 func ApplyMask(ip *Ip) [4]byte {
 	subnet := tools.GetSubnetFromMask(ip.Mask)
 	var ans [4]byte
@@ -0,0 +1,160 @@
+package network
+
+import "fmt"
+
+// Port security limits the mac addrs an access port learns, a frame from one more addr
+// is a violation handled as configured on the port.
+
+type PortSecViolation string
+
+const (
+	PORTSEC_PROTECT  PortSecViolation = "protect"  // frames from the new addr are dropped silently
+	PORTSEC_RESTRICT PortSecViolation = "restrict" // dropped, counted and reported
+	PORTSEC_SHUTDOWN PortSecViolation = "shutdown" // the port is err-disabled
+
+	PORTSEC_DEF_MAXIMUM = 1
+	PORTSEC_MAX_MAXIMUM = 1024
+)
+
+type SecureMac struct {
+	Vlan uint16
+	Mac  Mac
+}
+
+type PortSecurity struct {
+	Maximum    int
+	Violation  PortSecViolation
+	Sticky     bool
+	StickyMacs []SecureMac // learned while sticky, they are part of the config of the port and never age
+}
+
+func IsValidPortSecViolation(violation PortSecViolation) bool {
+	return violation == PORTSEC_PROTECT || violation == PORTSEC_RESTRICT || violation == PORTSEC_SHUTDOWN
+}
+
+// GetIntfPortSecurity returns the port security of the port, nil when it is disabled
+func GetIntfPortSecurity(intf *Interface) *PortSecurity {
+	return intf.prop.portSec
+}
+
+func IsIntfStickyMac(intf *Interface, vlan uint16, mac [6]byte) bool {
+	if sec := intf.prop.portSec; sec != nil {
+		for _, val := range sec.StickyMacs {
+			if val.Vlan == vlan && val.Mac.Addr == mac {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyPortSecurity returns a copy to modify, the frames being received keep reading the current one
+func copyPortSecurity(sec *PortSecurity) *PortSecurity {
+	if sec == nil {
+		return &PortSecurity{Maximum: PORTSEC_DEF_MAXIMUM, Violation: PORTSEC_SHUTDOWN}
+	}
+	dup := *sec
+	dup.StickyMacs = append([]SecureMac(nil), sec.StickyMacs...)
+	return &dup
+}
+
+// portSecIntf returns the access port to configure, port security is turned on with the
+// default settings if it wasn't yet
+func portSecIntf(node *Node, name string) (*Interface, *PortSecurity, error) {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if IsIntfIp(intf) || intf.prop.l2Mode != ACCESS {
+		return nil, nil, fmt.Errorf("Interface: %s is not an access port", node.Name+":"+name)
+	}
+	return intf, copyPortSecurity(intf.prop.portSec), nil
+}
+
+func NodeSetIntfPortSecurity(node *Node, name string) error {
+	intf, sec, err := portSecIntf(node, name)
+	if err != nil {
+		return err
+	}
+	intf.prop.portSec = sec
+	return nil
+}
+
+func NodeSetIntfPortSecMaximum(node *Node, name string, maximum int) error {
+	intf, sec, err := portSecIntf(node, name)
+	if err != nil {
+		return err
+	}
+	if maximum < 1 || maximum > PORTSEC_MAX_MAXIMUM {
+		return fmt.Errorf("Invalid maximum: %d, it goes from 1 to %d", maximum, PORTSEC_MAX_MAXIMUM)
+	}
+	if maximum < len(sec.StickyMacs) {
+		return fmt.Errorf("Interface: %s already has %d sticky mac addrs", node.Name+":"+name, len(sec.StickyMacs))
+	}
+
+	sec.Maximum = maximum
+	intf.prop.portSec = sec
+	return nil
+}
+
+func NodeSetIntfPortSecViolation(node *Node, name string, violation PortSecViolation) error {
+	intf, sec, err := portSecIntf(node, name)
+	if err != nil {
+		return err
+	}
+	if !IsValidPortSecViolation(violation) {
+		return fmt.Errorf("Unknown violation mode: %s", violation)
+	}
+
+	sec.Violation = violation
+	intf.prop.portSec = sec
+	return nil
+}
+
+// NodeSetIntfPortSecSticky turns sticky learning on or off, the sticky addrs are
+// dropped from the config when it is turned off
+func NodeSetIntfPortSecSticky(node *Node, name string, sticky bool) error {
+	intf, sec, err := portSecIntf(node, name)
+	if err != nil {
+		return err
+	}
+
+	sec.Sticky = sticky
+	if !sticky {
+		sec.StickyMacs = nil
+	}
+	intf.prop.portSec = sec
+	return nil
+}
+
+// NodeAddIntfStickyMac saves a mac addr learned on a sticky port into its config
+func NodeAddIntfStickyMac(intf *Interface, vlan uint16, mac [6]byte) error {
+	sec := intf.prop.portSec
+	if sec == nil || !sec.Sticky {
+		return fmt.Errorf("Interface: %s doesn't learn sticky mac addrs", intf.Name)
+	}
+	if IsIntfStickyMac(intf, vlan, mac) {
+		return nil
+	}
+	if len(sec.StickyMacs) >= sec.Maximum {
+		return fmt.Errorf("Interface: %s has reached its maximum of mac addrs", intf.Name)
+	}
+
+	sec = copyPortSecurity(sec)
+	sec.StickyMacs = append(sec.StickyMacs, SecureMac{Vlan: vlan, Mac: Mac{Addr: mac}})
+	intf.prop.portSec = sec
+	return nil
+}
+
+func NodeUnsetIntfPortSecurity(node *Node, name string) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.prop.portSec == nil {
+		return fmt.Errorf("Interface: %s has no port security", node.Name+":"+name)
+	}
+
+	intf.prop.portSec = nil
+	return nil
+}
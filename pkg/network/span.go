@@ -0,0 +1,148 @@
+package network
+
+import "fmt"
+
+// A monitor session (SPAN) copies the frames received and/or sent on its source ports out
+// of its destination port, where a sniffer can capture them. The destination port does
+// nothing else: the frames it receives are dropped and nothing is switched or routed out of it.
+
+type MonitorDir string
+
+const (
+	MONITOR_RX   MonitorDir = "rx"
+	MONITOR_TX   MonitorDir = "tx"
+	MONITOR_BOTH MonitorDir = "both"
+
+	MONITOR_MAX_SESSION = 66
+)
+
+type MonitorSource struct {
+	Intf string
+	Dir  MonitorDir
+}
+
+type MonitorSession struct {
+	Id          uint16
+	Sources     []MonitorSource
+	Destination string
+	Vlan        uint16 // only the frames of this vlan are copied, 0 for all of them
+}
+
+func IsValidMonitorDir(dir MonitorDir) bool {
+	return dir == MONITOR_RX || dir == MONITOR_TX || dir == MONITOR_BOTH
+}
+
+// IsMonitorSource tells whether the session copies the frames going in the given direction,
+// either MONITOR_RX or MONITOR_TX, on the port
+func IsMonitorSource(session *MonitorSession, name string, dir MonitorDir) bool {
+	for _, src := range session.Sources {
+		if src.Intf == name && (src.Dir == dir || src.Dir == MONITOR_BOTH) {
+			return true
+		}
+	}
+	return false
+}
+
+func GetNodeMonitorSessions(node *Node) []*MonitorSession {
+	return node.prop.monitor
+}
+
+// IsIntfMonitorDest tells whether the port is the destination of a monitor session
+func IsIntfMonitorDest(intf *Interface) bool {
+	if intf.Att_node == nil {
+		return false
+	}
+	for _, session := range intf.Att_node.prop.monitor {
+		if session.Destination == intf.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeSetMonitorSession adds a source port to the session, creating it if needed. The
+// destination and vlan filter given replace those of the session.
+func NodeSetMonitorSession(node *Node, id uint16, source string, dir MonitorDir, destination string, vlan uint16) error {
+	if _, err := GetIntfByIntfName(node, source); err != nil {
+		return err
+	}
+	dst, err := GetIntfByIntfName(node, destination)
+	if err != nil {
+		return err
+	}
+	if id < 1 || id > MONITOR_MAX_SESSION {
+		return fmt.Errorf("Invalid session: %d, sessions go from 1 to %d", id, MONITOR_MAX_SESSION)
+	}
+	if !IsValidMonitorDir(dir) {
+		return fmt.Errorf("Unknown monitor direction: %s", dir)
+	}
+	if vlan != 0 && !IsValidVlan(vlan) {
+		return fmt.Errorf("Invalid vlan: %d, vlans go from %d to %d", vlan, VLAN_MIN_ID, VLAN_MAX_ID)
+	}
+	if source == destination {
+		return fmt.Errorf("Interface: %s can't be both source and destination", node.Name+":"+source)
+	}
+	if dst.conn == nil {
+		return fmt.Errorf("Interface: %s is not wired, it can't be a monitor destination", node.Name+":"+destination)
+	}
+	if IsIntfIp(dst) {
+		return fmt.Errorf("Interface: %s has an ip addr, it can't be a monitor destination", node.Name+":"+destination)
+	}
+
+	// copies of copies would loop, a port is either a source or a destination
+	var session *MonitorSession
+	for _, other := range node.prop.monitor {
+		if other.Id == id {
+			session = other
+			continue
+		}
+		if other.Destination == source || other.Destination == destination {
+			return fmt.Errorf("Interface: %s is already the destination of session %d", node.Name+":"+other.Destination, other.Id)
+		}
+		if IsMonitorSource(other, destination, MONITOR_RX) || IsMonitorSource(other, destination, MONITOR_TX) {
+			return fmt.Errorf("Interface: %s is a source of session %d", node.Name+":"+destination, other.Id)
+		}
+	}
+
+	dup := &MonitorSession{Id: id, Destination: destination, Vlan: vlan}
+	if session != nil {
+		for _, src := range session.Sources {
+			if src.Intf == destination {
+				return fmt.Errorf("Interface: %s is a source of session %d", node.Name+":"+destination, id)
+			}
+			if src.Intf != source {
+				dup.Sources = append(dup.Sources, src)
+			}
+		}
+	}
+	dup.Sources = append(dup.Sources, MonitorSource{Intf: source, Dir: dir})
+
+	// the sessions are read by every frame, they are replaced rather than modified
+	sessions := []*MonitorSession{}
+	for _, other := range node.prop.monitor {
+		if other.Id == id {
+			other = dup
+		}
+		sessions = append(sessions, other)
+	}
+	if session == nil {
+		sessions = append(sessions, dup)
+	}
+	node.prop.monitor = sessions
+	return nil
+}
+
+func NodeUnsetMonitorSession(node *Node, id uint16) error {
+	sessions := []*MonitorSession{}
+	for _, other := range node.prop.monitor {
+		if other.Id != id {
+			sessions = append(sessions, other)
+		}
+	}
+	if len(sessions) == len(node.prop.monitor) {
+		return fmt.Errorf("No monitor session %d on node: %s", id, node.Name)
+	}
+
+	node.prop.monitor = sessions
+	return nil
+}
@@ -0,0 +1,144 @@
+package network
+
+import "fmt"
+
+// Storm control limits the broadcast, multicast and unknown unicast frames a L2 port lets in,
+// these are the frames flooded to every other port of the vlan.
+
+type StormType string
+
+const (
+	STORM_BROADCAST StormType = "broadcast"
+	STORM_MULTICAST StormType = "multicast"
+	STORM_UNICAST   StormType = "unicast" // unicast frames to a mac addr missing from the mac table
+)
+
+var StormTypes = []StormType{STORM_BROADCAST, STORM_MULTICAST, STORM_UNICAST}
+
+type StormAction string
+
+const (
+	STORM_DROP     StormAction = "drop"     // frames above the level are dropped
+	STORM_SHUTDOWN StormAction = "shutdown" // the port is err-disabled as soon as a level is exceeded
+)
+
+type StormControl struct {
+	Levels map[StormType]uint32 // packets per second let in, types without a level aren't limited
+	Action StormAction
+}
+
+func IsValidStormType(kind StormType) bool {
+	for _, val := range StormTypes {
+		if val == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIntfStormControl returns the storm control of the port, nil when no level is configured
+func GetIntfStormControl(intf *Interface) *StormControl {
+	return intf.prop.storm
+}
+
+func IsIntfErrDisabled(intf *Interface) bool {
+	return intf.prop.errDisabled
+}
+
+// copyStormControl returns a copy to modify, the frames being received keep reading the current one
+func copyStormControl(storm *StormControl) *StormControl {
+	dup := &StormControl{Levels: map[StormType]uint32{}, Action: STORM_DROP}
+	if storm != nil {
+		for kind, pps := range storm.Levels {
+			dup.Levels[kind] = pps
+		}
+		dup.Action = storm.Action
+	}
+	return dup
+}
+
+func validStormIntf(node *Node, intf *Interface) error {
+	if IsIntfIp(intf) || IsIntfSvi(intf) {
+		return fmt.Errorf("Interface: %s is not a L2 port", node.Name+":"+intf.Name)
+	}
+	if intf.prop.channel != nil {
+		return fmt.Errorf("Interface: %s is part of %s, configure the port-channel instead", node.Name+":"+intf.Name, intf.prop.channel.Name)
+	}
+	return nil
+}
+
+func NodeSetIntfStormLevel(node *Node, name string, kind StormType, pps uint32) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if err := validStormIntf(node, intf); err != nil {
+		return err
+	}
+	if !IsValidStormType(kind) {
+		return fmt.Errorf("Unknown storm control type: %s", kind)
+	}
+
+	storm := copyStormControl(intf.prop.storm)
+	storm.Levels[kind] = pps
+	intf.prop.storm = storm
+	return nil
+}
+
+// NodeUnsetIntfStormLevel removes the level of the given type, or every level when kind is empty
+func NodeUnsetIntfStormLevel(node *Node, name string, kind StormType) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.prop.storm == nil {
+		return fmt.Errorf("Interface: %s has no storm control", node.Name+":"+name)
+	}
+
+	if kind == "" {
+		intf.prop.storm = nil
+		return nil
+	}
+	if _, ok := intf.prop.storm.Levels[kind]; !ok {
+		return fmt.Errorf("Interface: %s has no %s storm control", node.Name+":"+name, kind)
+	}
+	storm := copyStormControl(intf.prop.storm)
+	delete(storm.Levels, kind)
+	if len(storm.Levels) == 0 {
+		storm = nil
+	}
+	intf.prop.storm = storm
+	return nil
+}
+
+func NodeSetIntfStormAction(node *Node, name string, action StormAction) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if intf.prop.storm == nil {
+		return fmt.Errorf("Interface: %s has no storm control level", node.Name+":"+name)
+	}
+	if action != STORM_DROP && action != STORM_SHUTDOWN {
+		return fmt.Errorf("Unknown storm control action: %s", action)
+	}
+
+	storm := copyStormControl(intf.prop.storm)
+	storm.Action = action
+	intf.prop.storm = storm
+	return nil
+}
+
+// NodeErrDisableIntf shuts the port down because of a violation, it stays down
+// until it is brought back up by hand
+func NodeErrDisableIntf(node *Node, name string) error {
+	intf, err := GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if err := NodeSetIntfShutdown(node, name, true); err != nil {
+		return err
+	}
+	intf.prop.errDisabled = true
+	return nil
+}
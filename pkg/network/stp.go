@@ -0,0 +1,62 @@
+package network
+
+import "time"
+
+type StpRole string
+
+const (
+	STP_ROOT       StpRole = "root"
+	STP_DESIGNATED StpRole = "designated"
+	STP_ALTERNATE  StpRole = "alternate"
+	STP_NO_ROLE    StpRole = "disabled"
+)
+
+type StpState string
+
+const (
+	STP_DISABLED   StpState = "disabled"
+	STP_BLOCKING   StpState = "blocking"
+	STP_LISTENING  StpState = "listening"
+	STP_LEARNING   StpState = "learning"
+	STP_FORWARDING StpState = "forwarding"
+)
+
+// StpVector is the spanning tree priority vector carried by bpdus, the lowest one wins
+type StpVector struct {
+	RootId   uint64 // bridge priority in the top 16 bits, mac addr below
+	RootCost uint32
+	BridgeId uint64 // bridge that sent the bpdu
+	PortId   uint16 // port it was sent on
+}
+
+type StpPort struct {
+	Id      uint16
+	Cost    uint32
+	Role    StpRole
+	State   StpState
+	Since   time.Time  // last change of State
+	IsEdge  bool       // port facing a host, it forwards right away
+	Heard   *StpVector // best info received on the port, nil until a bpdu arrives
+	HeardAt time.Time
+}
+
+// StpBridge is the spanning tree instance of a node switching frames
+type StpBridge struct {
+	Enabled   bool
+	Priority  uint16
+	Mac       Mac
+	RootId    uint64
+	RootCost  uint32
+	RootPort  string    // empty while the bridge is the root
+	TcUntil   time.Time // topology change announced in the bpdus until then
+	LastHello time.Time
+	Ports     map[string]*StpPort
+}
+
+func GetNodeStp(node *Node) *StpBridge {
+	return node.prop.stp
+}
+
+func AssignNodeStp(node *Node, stp *StpBridge) {
+	node.prop.stp = stp
+}
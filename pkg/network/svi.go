@@ -0,0 +1,47 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A switched virtual interface gives a switch an ip addr in one of its vlans, it isn't wired
+// to anything: its frames are switched in the vlan like those of any port.
+
+func SviName(vlan uint16) string {
+	return fmt.Sprintf("vlan%d", vlan)
+}
+
+// ParseSviName returns the vlan of an interface name of the form vlan<id>
+func ParseSviName(name string) (uint16, bool) {
+	if !strings.HasPrefix(name, "vlan") {
+		return 0, false
+	}
+	num, err := strconv.Atoi(strings.TrimPrefix(name, "vlan"))
+	if err != nil || num < VLAN_MIN_ID || num > VLAN_MAX_ID || SviName(uint16(num)) != name {
+		return 0, false
+	}
+	return uint16(num), true
+}
+
+func NodeAddSvi(node *Node, vlan uint16) (*Interface, error) {
+	if !IsValidVlan(vlan) {
+		return nil, fmt.Errorf("Invalid vlan: %d, vlans go from %d to %d", vlan, VLAN_MIN_ID, VLAN_MAX_ID)
+	}
+	name := SviName(vlan)
+	if _, err := GetIntfByIntfName(node, name); err == nil {
+		return nil, fmt.Errorf("Interface: %s already exists", node.Name+":"+name)
+	}
+
+	i, err := getNodeIntfAvailableSlot(node)
+	if err != nil {
+		return nil, fmt.Errorf("Node available slots in node: %s", node.Name)
+	}
+	intf := &Interface{Name: name, Att_node: node}
+	intf.prop.l2Mode = UNKNOWN
+	intf.prop.sviVlan = vlan
+	node.Intf[i] = intf
+
+	return intf, nil
+}
@@ -0,0 +1,609 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/tools"
+)
+
+/* A topology file is a JSON document of the form
+
+{
+    "name": "3 node linear topo",
+    "nodes": [
+        {"name": "R1", "loopback": "122.1.1.1"},
+        {"name": "R2", "loopback": "122.1.1.2"}
+    ],
+    "links": [
+        {
+            "from": {"node": "R1", "intf": "eth0/1", "ip": "10.1.1.1/24"},
+            "to":   {"node": "R2", "intf": "eth0/2", "mode": "trunk", "vlans": [10, 11]},
+            "cost": 1,
+            "impairment": {"delay_ms": 10, "jitter_ms": 2, "jitter_dist": "normal", "loss": 0.5,
+                           "duplicate": 0, "reorder": 0, "bandwidth_kbps": 1000}
+        }
+    ]
+}
+
+An endpoint is either L3 (ip) or L2 (mode + vlans, plus native_vlan on a trunk), never both.
+Either kind takes an "mtu", 1500 bytes by default.
+Access endpoints take "port_security": {"maximum": 2, "violation": "restrict", "sticky": true},
+the addrs learned while sticky are saved as "sticky_macs" from show node <n> port-security.
+L2 endpoints are bundled into a port-channel with "channel_group": 1 and "channel_mode"
+(on, active or passive, on by default), the first member gives its L2 mode and vlans to the bundle.
+A switch node routes between its vlans through vlan interfaces, i.e.
+"svis": [{"vlan": 10, "ip": "10.1.1.254/24"}]. Static routes aren't part of the topology, a script
+next to it configures them, i.e. -script topologies/inter-vlan.txt. A node mirrors ports with
+monitor sessions, i.e.
+"monitor": [{"session": 1, "sources": [{"intf": "eth0/2", "dir": "rx"}], "destination": "eth0/8", "vlan": 10}],
+dir is both and every vlan is copied by default. */
+
+type TopoEndpoint struct {
+	Node         string      `json:"node"`
+	Intf         string      `json:"intf"`
+	Ip           string      `json:"ip,omitempty"`
+	Mode         L2Mode      `json:"mode,omitempty"`
+	Vlans        []uint16    `json:"vlans,omitempty"`
+	NativeVlan   uint16      `json:"native_vlan,omitempty"`
+	ChannelGroup uint16      `json:"channel_group,omitempty"`
+	ChannelMode  ChannelMode `json:"channel_mode,omitempty"`
+	Mtu          int         `json:"mtu,omitempty"`
+
+	PortSecurity *TopoPortSecurity `json:"port_security,omitempty"`
+}
+
+// TopoPortSecurity is the port security of an access port, sticky_macs are the addrs
+// the port learned while sticky, they are secured in the vlan of the port
+type TopoPortSecurity struct {
+	Maximum    int              `json:"maximum,omitempty"`
+	Violation  PortSecViolation `json:"violation,omitempty"`
+	Sticky     bool             `json:"sticky,omitempty"`
+	StickyMacs []string         `json:"sticky_macs,omitempty"`
+}
+
+type TopoNode struct {
+	Name     string        `json:"name"`
+	Loopback string        `json:"loopback,omitempty"`
+	Svis     []TopoSvi     `json:"svis,omitempty"`
+	Monitor  []TopoMonitor `json:"monitor,omitempty"`
+	line     int
+}
+
+// TopoSvi is a vlan interface of a switch node
+type TopoSvi struct {
+	Vlan uint16 `json:"vlan"`
+	Ip   string `json:"ip"`
+}
+
+// TopoMonitor is a monitor session of a node
+type TopoMonitor struct {
+	Session     uint16              `json:"session"`
+	Sources     []TopoMonitorSource `json:"sources"`
+	Destination string              `json:"destination"`
+	Vlan        uint16              `json:"vlan,omitempty"`
+}
+
+type TopoMonitorSource struct {
+	Intf string     `json:"intf"`
+	Dir  MonitorDir `json:"dir,omitempty"`
+}
+
+type TopoImpairment struct {
+	DelayMs       float64    `json:"delay_ms,omitempty"`
+	JitterMs      float64    `json:"jitter_ms,omitempty"`
+	JitterDist    JitterDist `json:"jitter_dist,omitempty"`
+	Loss          float64    `json:"loss,omitempty"`
+	Duplicate     float64    `json:"duplicate,omitempty"`
+	Reorder       float64    `json:"reorder,omitempty"`
+	BandwidthKbps uint64     `json:"bandwidth_kbps,omitempty"`
+}
+
+type TopoLink struct {
+	From       TopoEndpoint    `json:"from"`
+	To         TopoEndpoint    `json:"to"`
+	Cost       uint            `json:"cost"`
+	Impairment *TopoImpairment `json:"impairment,omitempty"`
+	line       int
+}
+
+type Topology struct {
+	Name  string     `json:"name"`
+	Nodes []TopoNode `json:"nodes"`
+	Links []TopoLink `json:"links"`
+}
+
+// LoadTopology reads, validates and builds the graph described by the given file.
+// No sockets are opened here, that is left to the caller once the graph is known to be sane.
+func LoadTopology(path string) (*Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read topology file: %s", path)
+	}
+
+	topo, err := parseTopology(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+
+	if errs := validateTopology(topo); len(errs) > 0 {
+		return nil, fmt.Errorf("%s:%s", path, strings.Join(errs, "\n"+path+":"))
+	}
+
+	return buildTopology(topo)
+}
+
+func parseTopology(data []byte) (*Topology, error) {
+	topo := &Topology{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// misspelled keys of the nodes and links are errors too, rather than silently ignored
+	dec.DisallowUnknownFields()
+
+	if err := expectDelim(dec, data, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, jsonError(data, dec, err)
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "name":
+			if err = dec.Decode(&topo.Name); err != nil {
+				err = jsonError(data, dec, err)
+			}
+		case "nodes":
+			var lines []int
+			topo.Nodes, lines, err = decodeArray[TopoNode](dec, data)
+			for i := range lines {
+				topo.Nodes[i].line = lines[i]
+			}
+		case "links":
+			var lines []int
+			topo.Links, lines, err = decodeArray[TopoLink](dec, data)
+			for i := range lines {
+				topo.Links[i].line = lines[i]
+			}
+		default:
+			return nil, fmt.Errorf("%d: unknown key '%s'", lineAt(data, dec.InputOffset()), key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, data, '}'); err != nil {
+		return nil, err
+	}
+
+	return topo, nil
+}
+
+// decodeArray decodes a json array element by element so that the line of each element can be remembered
+func decodeArray[T any](dec *json.Decoder, data []byte) ([]T, []int, error) {
+	if err := expectDelim(dec, data, '['); err != nil {
+		return nil, nil, err
+	}
+
+	var items []T
+	var lines []int
+	for dec.More() {
+		start := skipSeparators(data, dec.InputOffset())
+
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return nil, nil, fmt.Errorf("%d: %v", lineAt(data, start), err)
+		}
+		items = append(items, item)
+		lines = append(lines, lineAt(data, start))
+	}
+
+	if err := expectDelim(dec, data, ']'); err != nil {
+		return nil, nil, err
+	}
+	return items, lines, nil
+}
+
+func expectDelim(dec *json.Decoder, data []byte, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return jsonError(data, dec, err)
+	}
+	if tok != delim {
+		return fmt.Errorf("%d: expected '%v'", lineAt(data, dec.InputOffset()), delim)
+	}
+	return nil
+}
+
+func jsonError(data []byte, dec *json.Decoder, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%d: %v", lineAt(data, syntaxErr.Offset), err)
+	}
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("%d: %v", lineAt(data, typeErr.Offset), err)
+	}
+	return fmt.Errorf("%d: %v", lineAt(data, dec.InputOffset()), err)
+}
+
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func parseCidr(str string) (*Ip, error) {
+	parts := strings.Split(str, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("'%s' is not of the form a.b.c.d/mask", str)
+	}
+	addr, err := parseIp(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	mask, err := strconv.Atoi(parts[1])
+	if err != nil || mask < 0 || mask > 32 {
+		return nil, fmt.Errorf("'%s' has an invalid mask", str)
+	}
+
+	return &Ip{Addr: addr, Mask: uint8(mask)}, nil
+}
+
+func parseIp(str string) ([4]byte, error) {
+	var ans [4]byte
+	parts := strings.Split(str, ".")
+	if len(parts) != 4 {
+		return ans, fmt.Errorf("'%s' is not a valid ip addr", str)
+	}
+	for i, val := range parts {
+		num, err := strconv.Atoi(val)
+		if err != nil || num < 0 || num > 255 {
+			return ans, fmt.Errorf("'%s' is not a valid ip addr", str)
+		}
+		ans[i] = uint8(num)
+	}
+	return ans, nil
+}
+
+// subnetsOverlap reports whether one of the subnets contains the other
+func subnetsOverlap(a, b *Ip) bool {
+	mask := a.Mask
+	if b.Mask < mask {
+		mask = b.Mask
+	}
+	return ApplyMask(&Ip{Addr: a.Addr, Mask: mask}) == ApplyMask(&Ip{Addr: b.Addr, Mask: mask})
+}
+
+func convertImpairment(t *TopoImpairment) Impairment {
+	return Impairment{
+		Delay:      time.Duration(t.DelayMs * float64(time.Millisecond)),
+		Jitter:     time.Duration(t.JitterMs * float64(time.Millisecond)),
+		JitterDist: t.JitterDist,
+		Loss:       t.Loss,
+		Duplicate:  t.Duplicate,
+		Reorder:    t.Reorder,
+		Bandwidth:  t.BandwidthKbps * 1000,
+	}
+}
+
+func validateTopology(topo *Topology) []string {
+	var errs []string
+	report := func(line int, format string, args ...any) {
+		errs = append(errs, fmt.Sprintf("%d: ", line)+fmt.Sprintf(format, args...))
+	}
+
+	type intfInfo struct {
+		line int
+		ip   *Ip
+	}
+	nodes := map[string]int{}
+	intfs := map[string]map[string]intfInfo{}
+
+	for _, node := range topo.Nodes {
+		if node.Name == "" {
+			report(node.line, "node without a name")
+			continue
+		}
+		if line, ok := nodes[node.Name]; ok {
+			report(node.line, "duplicate node '%s', first defined at line %d", node.Name, line)
+			continue
+		}
+		nodes[node.Name] = node.line
+		intfs[node.Name] = map[string]intfInfo{}
+
+		if node.Loopback != "" {
+			if _, err := parseIp(node.Loopback); err != nil {
+				report(node.line, "node '%s': %v", node.Name, err)
+			}
+		}
+
+		for _, svi := range node.Svis {
+			if !IsValidVlan(svi.Vlan) {
+				report(node.line, "node '%s' has a vlan interface with invalid vlan %d", node.Name, svi.Vlan)
+				continue
+			}
+			name := SviName(svi.Vlan)
+			if _, ok := intfs[node.Name][name]; ok {
+				report(node.line, "duplicate interface '%s' on node '%s'", name, node.Name)
+				continue
+			}
+			ip, err := parseCidr(svi.Ip)
+			if err != nil {
+				report(node.line, "interface '%s:%s': %v", node.Name, name, err)
+				continue
+			}
+			for other, info := range intfs[node.Name] {
+				if subnetsOverlap(ip, info.ip) {
+					report(node.line, "interface '%s:%s' subnet %s overlaps with interface '%s'", node.Name, name, svi.Ip, other)
+				}
+			}
+			intfs[node.Name][name] = intfInfo{line: node.line, ip: ip}
+		}
+	}
+
+	for _, link := range topo.Links {
+		if link.Impairment != nil {
+			impair := convertImpairment(link.Impairment)
+			if err := validImpairment(&impair); err != nil {
+				report(link.line, "link impairment: %v", err)
+			}
+		}
+
+		for _, end := range []TopoEndpoint{link.From, link.To} {
+			if _, ok := nodes[end.Node]; !ok {
+				report(link.line, "link refers to unknown node '%s'", end.Node)
+				continue
+			}
+			if end.Intf == "" {
+				report(link.line, "link endpoint on node '%s' has no interface name", end.Node)
+				continue
+			}
+			if old, ok := intfs[end.Node][end.Intf]; ok {
+				report(link.line, "duplicate interface '%s' on node '%s', first defined at line %d", end.Intf, end.Node, old.line)
+				continue
+			}
+			if len(intfs[end.Node]) >= MAX_INTF_PER_NODE {
+				report(link.line, "node '%s' has more than %d interfaces", end.Node, MAX_INTF_PER_NODE)
+				continue
+			}
+
+			info := intfInfo{line: link.line}
+			if end.Ip != "" {
+				if end.Mode != "" || len(end.Vlans) > 0 {
+					report(link.line, "interface '%s:%s' can't have both an ip addr and a L2 mode", end.Node, end.Intf)
+				}
+				ip, err := parseCidr(end.Ip)
+				if err != nil {
+					report(link.line, "interface '%s:%s': %v", end.Node, end.Intf, err)
+				} else {
+					for name, other := range intfs[end.Node] {
+						if other.ip != nil && subnetsOverlap(ip, other.ip) {
+							report(link.line, "interface '%s:%s' subnet %s overlaps with interface '%s' at line %d", end.Node, end.Intf, end.Ip, name, other.line)
+						}
+					}
+					info.ip = ip
+				}
+			} else if end.Mode != "" {
+				if end.Mode != ACCESS && end.Mode != TRUNK {
+					report(link.line, "interface '%s:%s' has unknown L2 mode '%s'", end.Node, end.Intf, end.Mode)
+				}
+				if end.Mode == ACCESS && len(end.Vlans) > 1 {
+					report(link.line, "access interface '%s:%s' can only be member of a single vlan", end.Node, end.Intf)
+				}
+				if len(end.Vlans) > MAX_VLAN_MEMBERSHIP {
+					report(link.line, "interface '%s:%s' is member of more than %d vlans", end.Node, end.Intf, MAX_VLAN_MEMBERSHIP)
+				}
+				for _, vlan := range end.Vlans {
+					if !IsValidVlan(vlan) {
+						report(link.line, "interface '%s:%s' has invalid vlan %d", end.Node, end.Intf, vlan)
+					}
+				}
+				if end.NativeVlan != 0 && end.Mode != TRUNK {
+					report(link.line, "interface '%s:%s' has a native vlan but is not a trunk", end.Node, end.Intf)
+				} else if end.NativeVlan != 0 && !IsValidVlan(end.NativeVlan) {
+					report(link.line, "interface '%s:%s' has invalid native vlan %d", end.Node, end.Intf, end.NativeVlan)
+				}
+			} else if len(end.Vlans) > 0 || end.NativeVlan != 0 {
+				report(link.line, "interface '%s:%s' has vlans but no L2 mode", end.Node, end.Intf)
+			}
+			if sec := end.PortSecurity; sec != nil {
+				if end.Mode != ACCESS {
+					report(link.line, "interface '%s:%s' has port security but is not an access port", end.Node, end.Intf)
+				}
+				if sec.Maximum < 0 || sec.Maximum > PORTSEC_MAX_MAXIMUM {
+					report(link.line, "interface '%s:%s' has invalid port security maximum %d", end.Node, end.Intf, sec.Maximum)
+				}
+				if sec.Violation != "" && !IsValidPortSecViolation(sec.Violation) {
+					report(link.line, "interface '%s:%s' has unknown port security violation mode '%s'", end.Node, end.Intf, sec.Violation)
+				}
+				if len(sec.StickyMacs) > 0 && !sec.Sticky {
+					report(link.line, "interface '%s:%s' has sticky mac addrs but isn't sticky", end.Node, end.Intf)
+				}
+				if len(sec.StickyMacs) > max(sec.Maximum, PORTSEC_DEF_MAXIMUM) {
+					report(link.line, "interface '%s:%s' has more sticky mac addrs than its maximum", end.Node, end.Intf)
+				}
+				for _, mac := range sec.StickyMacs {
+					if hw, err := net.ParseMAC(mac); err != nil || len(hw) != 6 {
+						report(link.line, "interface '%s:%s' has invalid sticky mac addr '%s'", end.Node, end.Intf, mac)
+					}
+				}
+			}
+			if end.ChannelGroup != 0 && end.Mode == "" {
+				report(link.line, "interface '%s:%s' is in a port-channel but has no L2 mode", end.Node, end.Intf)
+			}
+			if end.ChannelMode != "" && end.ChannelMode != CHANNEL_ON && end.ChannelMode != CHANNEL_ACTIVE && end.ChannelMode != CHANNEL_PASSIVE {
+				report(link.line, "interface '%s:%s' has unknown port-channel mode '%s'", end.Node, end.Intf, end.ChannelMode)
+			} else if end.ChannelMode != "" && end.ChannelGroup == 0 {
+				report(link.line, "interface '%s:%s' has a port-channel mode but no channel group", end.Node, end.Intf)
+			}
+			if end.Mtu != 0 && (end.Mtu < INTF_MIN_MTU || end.Mtu > INTF_MAX_MTU) {
+				report(link.line, "interface '%s:%s' has invalid mtu %d, it goes from %d to %d", end.Node, end.Intf, end.Mtu, INTF_MIN_MTU, INTF_MAX_MTU)
+			}
+			intfs[end.Node][end.Intf] = info
+		}
+	}
+
+	return errs
+}
+
+func buildTopology(topo *Topology) (*Graph, error) {
+	graph := CreateNewGraph(topo.Name)
+	for _, n := range topo.Nodes {
+		node := CreateGraphNode(graph, n.Name)
+		if n.Loopback != "" {
+			NodeSetLbAddr(node, n.Loopback)
+		}
+	}
+
+	for _, link := range topo.Links {
+		node1, _ := GetNodeByNodeName(graph, link.From.Node)
+		node2, _ := GetNodeByNodeName(graph, link.To.Node)
+		if err := InsertLinkBetweenNodes(node1, node2, link.From.Intf, link.To.Intf, link.Cost); err != nil {
+			return nil, err
+		}
+		if link.Impairment != nil {
+			if err := SetLinkImpairment(node1, link.From.Intf, convertImpairment(link.Impairment)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, end := range []TopoEndpoint{link.From, link.To} {
+			node, _ := GetNodeByNodeName(graph, end.Node)
+			if end.Mtu != 0 {
+				if err := NodeSetIntfMtu(node, end.Intf, end.Mtu); err != nil {
+					return nil, err
+				}
+			}
+			if end.Ip != "" {
+				ip, _ := parseCidr(end.Ip)
+				NodeSetIntfIpAddr(node, end.Intf, tools.ConvertAddrToStr(ip.Addr[:]), ip.Mask)
+			} else if end.Mode != "" {
+				NodeSetIntfL2Mode(node, end.Intf, end.Mode)
+				for _, vlan := range end.Vlans {
+					if err := NodeSetIntfVlanMembership(node, end.Intf, vlan); err != nil {
+						return nil, err
+					}
+				}
+				if end.NativeVlan != 0 {
+					if err := NodeSetIntfNativeVlan(node, end.Intf, end.NativeVlan); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	for _, link := range topo.Links {
+		for _, end := range []TopoEndpoint{link.From, link.To} {
+			if end.PortSecurity != nil {
+				node, _ := GetNodeByNodeName(graph, end.Node)
+				if err := applyPortSecurity(node, end.Intf, end.PortSecurity); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// port-channels come after the ports, they take the L2 settings of their first member
+	for _, link := range topo.Links {
+		for _, end := range []TopoEndpoint{link.From, link.To} {
+			if end.ChannelGroup == 0 {
+				continue
+			}
+			mode := end.ChannelMode
+			if mode == "" {
+				mode = CHANNEL_ON
+			}
+			node, _ := GetNodeByNodeName(graph, end.Node)
+			if err := NodeSetIntfChannelGroup(node, end.Intf, end.ChannelGroup, mode); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// vlan interfaces come after the ports
+	for _, n := range topo.Nodes {
+		node, _ := GetNodeByNodeName(graph, n.Name)
+		for _, svi := range n.Svis {
+			intf, err := NodeAddSvi(node, svi.Vlan)
+			if err != nil {
+				return nil, err
+			}
+			ip, _ := parseCidr(svi.Ip)
+			NodeSetIntfIpAddr(node, intf.Name, tools.ConvertAddrToStr(ip.Addr[:]), ip.Mask)
+		}
+	}
+
+	// monitor sessions last, their ports must be fully configured
+	for _, n := range topo.Nodes {
+		node, _ := GetNodeByNodeName(graph, n.Name)
+		for _, session := range n.Monitor {
+			if len(session.Sources) == 0 {
+				return nil, fmt.Errorf("Monitor session %d of node: %s has no source", session.Session, n.Name)
+			}
+			for _, src := range session.Sources {
+				dir := src.Dir
+				if dir == "" {
+					dir = MONITOR_BOTH
+				}
+				if err := NodeSetMonitorSession(node, session.Session, src.Intf, dir, session.Destination, session.Vlan); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+func applyPortSecurity(node *Node, name string, sec *TopoPortSecurity) error {
+	if err := NodeSetIntfPortSecurity(node, name); err != nil {
+		return err
+	}
+	if sec.Maximum != 0 {
+		if err := NodeSetIntfPortSecMaximum(node, name, sec.Maximum); err != nil {
+			return err
+		}
+	}
+	if sec.Violation != "" {
+		if err := NodeSetIntfPortSecViolation(node, name, sec.Violation); err != nil {
+			return err
+		}
+	}
+	if !sec.Sticky {
+		return nil
+	}
+	if err := NodeSetIntfPortSecSticky(node, name, true); err != nil {
+		return err
+	}
+
+	intf, _ := GetIntfByIntfName(node, name)
+	vlan := GetIntfVlanMembership(intf)[0]
+	for _, mac := range sec.StickyMacs {
+		hw, _ := net.ParseMAC(mac)
+		var addr [6]byte
+		copy(addr[:], hw)
+		if err := NodeAddIntfStickyMac(intf, vlan, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package stack
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
 	"github.com/gkarthikreddi/tcp/tools"
@@ -10,7 +11,7 @@ import (
 const (
 	ARP_BROAD_REQ = 1
 	ARP_RPLY      = 2
-	ARP_MSG       = 806
+	ARP_MSG       = 0x0806
 )
 
 type arpHeader struct {
@@ -56,6 +57,19 @@ func deleteArpTableEntry(node *network.Node, ip *network.Ip) {
 	}
 }
 
+// flushArpTableIntf forgets the entries resolved on the interface, the static ones only with static set
+func flushArpTableIntf(node *network.Node, name string, static bool) {
+	dropArpPendingIntf(node, name)
+
+	arpLock.Lock()
+	defer arpLock.Unlock()
+	for entry := network.GetNodeArpTable(node); entry != nil; entry = entry.Next {
+		if entry.Name == name && (static || !entry.IsStatic) {
+			deleteArpTableEntry(node, entry.IpAddr)
+		}
+	}
+}
+
 func addArpTableEntry(node *network.Node, entry *network.ArpEntry) {
 	arpTable := network.GetNodeArpTable(node)
 	if arpTable == nil {
@@ -65,7 +79,12 @@ func addArpTableEntry(node *network.Node, entry *network.ArpEntry) {
 
 	oldEntry := arpTableLookup(arpTable, entry.IpAddr)
 	if oldEntry != nil {
-		if entry.MacAddr.Addr == oldEntry.MacAddr.Addr {
+		if oldEntry.IsStatic && !entry.IsStatic {
+			return
+		}
+		if entry.MacAddr.Addr == oldEntry.MacAddr.Addr && entry.State == oldEntry.State && entry.IsStatic == oldEntry.IsStatic {
+			oldEntry.Name = entry.Name
+			oldEntry.Updated = entry.Updated
 			return
 		} else {
 			deleteArpTableEntry(node, oldEntry.IpAddr)
@@ -76,6 +95,9 @@ func addArpTableEntry(node *network.Node, entry *network.ArpEntry) {
 
 	entry.Next = arpTable.Next
 	entry.Prev = arpTable
+	if arpTable.Next != nil {
+		arpTable.Next.Prev = entry
+	}
 	arpTable.Next = entry
 }
 
@@ -84,11 +106,68 @@ func updateArpTableFromArpReply(node *network.Node, arpReply *arpHeader, localIn
 		return
 	}
 
-	entry := network.ArpEntry{IpAddr: &network.Ip{Addr: arpReply.SrcProtocolAddr},
-		MacAddr: &network.Mac{Addr: arpReply.SrcMacAddr},
-		Name:    localIntf.Name}
+	learnArpSender(node, arpReply, localIntf, true)
+}
+
+// learnArpSender follows the packet reception of RFC 826: the binding of the sender is refreshed
+// when already known, and only added when the message was meant for us
+func learnArpSender(node *network.Node, arpFrame *arpHeader, localIntf *network.Interface, forUs bool) {
+	ip := &network.Ip{Addr: arpFrame.SrcProtocolAddr}
+	if ip.Addr == [4]byte{} {
+		return
+	}
 
-	addArpTableEntry(node, &entry)
+	arpLock.Lock()
+	if old := arpTableLookup(network.GetNodeArpTable(node), ip); (old == nil && !forUs) || (old != nil && old.IsStatic) {
+		arpLock.Unlock()
+		return
+	}
+	addArpTableEntry(node, &network.ArpEntry{IpAddr: ip,
+		MacAddr: &network.Mac{Addr: arpFrame.SrcMacAddr},
+		Name:    localIntf.Name,
+		State:   network.ARP_COMPLETE,
+		Updated: time.Now()})
+	arpLock.Unlock()
+
+	flushArpPending(node, ip)
+}
+
+// isDuplicateIp warns when someone else on the segment claims the ip addr of the interface
+func isDuplicateIp(node *network.Node, localIntf *network.Interface, arpFrame *arpHeader) bool {
+	if arpFrame.SrcProtocolAddr != network.GetIntfIp(localIntf).Addr || arpFrame.SrcMacAddr == network.GetIntfMac(localIntf).Addr {
+		return false
+	}
+
+	fmt.Println(Red + "Duplicate ip addr " + Yellow + tools.ConvertAddrToStr(arpFrame.SrcProtocolAddr[:]) + Red +
+		" on interface " + Yellow + node.Name + ":" + localIntf.Name + Red +
+		", also used by " + Yellow + tools.ConvertAddrToStr(arpFrame.SrcMacAddr[:]) + Reset)
+	return true
+}
+
+// sendGratuitousArp announces the ip and mac addr of the interface, so that the neighbors
+// update their arp tables and whoever else uses the same ip addr finds out
+func sendGratuitousArp(node *network.Node, intf *network.Interface) error {
+	if !network.IsIntfIp(intf) || !network.IsIntfUp(intf) {
+		return nil
+	}
+
+	etherFrame := ethernetHeader{SrcMacAddr: network.GetIntfMac(intf).Addr,
+		EtherType: ARP_MSG,
+	}
+	arpFrame := arpHeader{HardwareType: 1,
+		ProtocolType:    0x0800,
+		HardwareLength:  6,
+		ProtocolLength:  4,
+		Operation:       ARP_BROAD_REQ,
+		SrcMacAddr:      network.GetIntfMac(intf).Addr,
+		SrcProtocolAddr: network.GetIntfIp(intf).Addr,
+		DstProtocolAddr: network.GetIntfIp(intf).Addr}
+	fillBroadcastAddr(&etherFrame.DstMacAddr)
+
+	if err := assignPayload(&etherFrame, &arpFrame); err != nil {
+		return err
+	}
+	return sendPkt(&etherFrame, intf)
 }
 
 func SendArpBroadcast(node *network.Node, outIntf *network.Interface, ip *network.Ip) error {
@@ -133,6 +212,9 @@ func SendArpAll(node *network.Node) error {
         if intf == nil {
             break
         }
+		if !network.IsIntfUp(intf) {
+			continue
+		}
 		etherFrame := ethernetHeader{SrcMacAddr: network.GetIntfMac(intf).Addr,
 			EtherType: ARP_MSG,
 			Fcs:       0, // You shouldn't do this!
@@ -162,10 +244,18 @@ func SendArpAll(node *network.Node) error {
 func processArpBroadcast(node *network.Node, localIntf *network.Interface, etherFrame *ethernetHeader) error {
 	fmt.Println(Purple + "ARP braodcast msg recieved on interface " + Yellow + localIntf.Name + Purple + " of node " + Yellow + node.Name + Reset)
 
-	if arpFrame, err := tools.ByteToStruct(etherFrame.Payload[:], arpHeader{}); err == nil {
+	if arpFrame, err := decodeArp(etherFrame.Payload); err == nil {
+		if isDuplicateIp(node, localIntf, arpFrame) {
+			return nil
+		}
+
 		ip := arpFrame.DstProtocolAddr
-		if ip == network.GetIntfIp(localIntf).Addr || ip == [4]byte{255, 255, 255, 255} {
-			sendArpReply(etherFrame, localIntf)
+		forUs := ip == network.GetIntfIp(localIntf).Addr || ip == [4]byte{255, 255, 255, 255}
+		learnArpSender(node, arpFrame, localIntf, forUs)
+		if forUs {
+			sendArpReply(etherFrame, localIntf, network.GetIntfIp(localIntf).Addr)
+		} else if isProxyArpTarget(node, localIntf, arpFrame) {
+			sendArpReply(etherFrame, localIntf, ip)
 		}
 	} else {
 		return fmt.Errorf("Can't get arpFrame from ethernetFrame")
@@ -173,16 +263,39 @@ func processArpBroadcast(node *network.Node, localIntf *network.Interface, ether
 	return nil
 }
 
-func sendArpReply(etherFrame *ethernetHeader, outIntf *network.Interface) error {
+// isProxyArpTarget tells whether the interface answers on behalf of the requested ip addr,
+// that is when proxy arp is on and the addr is reached through another interface of the node
+func isProxyArpTarget(node *network.Node, localIntf *network.Interface, arpFrame *arpHeader) bool {
+	if !network.IsIntfProxyArp(localIntf) || arpFrame.SrcProtocolAddr == arpFrame.DstProtocolAddr {
+		return false
+	}
+
+	dstIp := &network.Ip{Addr: arpFrame.DstProtocolAddr}
+	route := nodeRouteLookup(node, dstIp.Addr)
+	if route == nil {
+		return false
+	}
+	if !isDirectRoute(route) {
+		return route.OutIntf != localIntf.Name
+	}
+	if isLocalDelivery(node, dstIp) {
+		return true
+	}
+	outIntf, err := network.NodeGetMatchingSubnet(node, dstIp)
+	return err == nil && outIntf != localIntf
+}
+
+// sendArpReply answers the request with the mac addr of the interface, ip is the addr the reply is about
+func sendArpReply(etherFrame *ethernetHeader, outIntf *network.Interface, ip [4]byte) error {
     var err error
-    if arpFrame, err := tools.ByteToStruct(etherFrame.Payload[:], arpHeader{}); err == nil {
+    if arpFrame, err := decodeArp(etherFrame.Payload); err == nil {
 		arpReplyFrame := arpHeader{HardwareType: 1,
 			ProtocolType:    0x0800,
 			HardwareLength:  6,
 			ProtocolLength:  4,
 			Operation:       ARP_RPLY,
 			SrcMacAddr:      network.GetIntfMac(outIntf).Addr,
-			SrcProtocolAddr: network.GetIntfIp(outIntf).Addr,
+			SrcProtocolAddr: ip,
 			DstMacAddr:      arpFrame.SrcMacAddr,
 			DstProtocolAddr: arpFrame.SrcProtocolAddr,
 		}
@@ -204,7 +317,10 @@ func sendArpReply(etherFrame *ethernetHeader, outIntf *network.Interface) error
 func processArpReply(node *network.Node, localIntf *network.Interface, etherFrame *ethernetHeader) error {
 	fmt.Println(Purple + "ARP reply msg recieved on interface " + Yellow + localIntf.Name + Purple + " of node " + Yellow + node.Name + Reset)
 
-	if arpFrame, err := tools.ByteToStruct(etherFrame.Payload[:], arpHeader{}); err == nil {
+	if arpFrame, err := decodeArp(etherFrame.Payload); err == nil {
+		if isDuplicateIp(node, localIntf, arpFrame) {
+			return nil
+		}
 		updateArpTableFromArpReply(node, arpFrame, localIntf)
 	} else {
 		return err
@@ -0,0 +1,119 @@
+package stack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Learned arp entries are forgotten once they haven't been refreshed for arpTimeout,
+// a sweeper goes through the arp table of every node once a second.
+
+const (
+	ARP_DEF_TIMEOUT    = time.Second * 300
+	ARP_AGING_INTERVAL = time.Second
+)
+
+var arpTimeout = ARP_DEF_TIMEOUT
+
+func SetArpTimeout(timeout time.Duration) {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+	arpTimeout = timeout
+}
+
+func GetArpTimeout() time.Duration {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+	return arpTimeout
+}
+
+func InitArpAging(graph *network.Graph) {
+	go func() {
+		for range time.Tick(ARP_AGING_INTERVAL) {
+			for node := graph.List; node != nil; node = node.Next {
+				ageArpTable(node)
+			}
+		}
+	}()
+}
+
+func ageArpTable(node *network.Node) {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	for entry := network.GetNodeArpTable(node); entry != nil; entry = entry.Next {
+		if !entry.IsStatic && entry.State == network.ARP_COMPLETE && time.Since(entry.Updated) > arpTimeout {
+			deleteArpTableEntry(node, entry.IpAddr)
+		}
+	}
+}
+
+func AddStaticArpEntry(node *network.Node, ip [4]byte, mac [6]byte, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if !network.IsIntfIp(intf) {
+		return fmt.Errorf("Interface: %s has no ip addr", name)
+	}
+
+	arpLock.Lock()
+	addArpTableEntry(node, &network.ArpEntry{IpAddr: &network.Ip{Addr: ip},
+		MacAddr:  &network.Mac{Addr: mac},
+		Name:     name,
+		State:    network.ARP_COMPLETE,
+		IsStatic: true,
+		Updated:  time.Now()})
+	arpLock.Unlock()
+
+	flushArpPending(node, &network.Ip{Addr: ip})
+	return nil
+}
+
+func DeleteStaticArpEntry(node *network.Node, ip [4]byte) error {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	entry := arpTableLookup(network.GetNodeArpTable(node), &network.Ip{Addr: ip})
+	if entry == nil || !entry.IsStatic {
+		return fmt.Errorf("No static arp entry for the given ip addr")
+	}
+	deleteArpTableEntry(node, entry.IpAddr)
+	return nil
+}
+
+// GetArpTable returns a copy of the arp table of the node
+func GetArpTable(node *network.Node) []network.ArpEntry {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	var entries []network.ArpEntry
+	for entry := network.GetNodeArpTable(node); entry != nil; entry = entry.Next {
+		dup := *entry
+		dup.IpAddr = &network.Ip{Addr: entry.IpAddr.Addr, Mask: entry.IpAddr.Mask}
+		if entry.MacAddr != nil {
+			dup.MacAddr = &network.Mac{Addr: entry.MacAddr.Addr}
+		}
+		dup.Next, dup.Prev = nil, nil
+		entries = append(entries, dup)
+	}
+	return entries
+}
+
+// ClearArpTable removes the learned entries of the node, or only the one of ip when given.
+// Static entries and entries still being resolved are left alone.
+func ClearArpTable(node *network.Node, ip *[4]byte) {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	for entry := network.GetNodeArpTable(node); entry != nil; entry = entry.Next {
+		if entry.IsStatic || entry.State != network.ARP_COMPLETE {
+			continue
+		}
+		if ip == nil || entry.IpAddr.Addr == *ip {
+			deleteArpTableEntry(node, entry.IpAddr)
+		}
+	}
+}
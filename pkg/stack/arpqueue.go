@@ -0,0 +1,130 @@
+package stack
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Frames whose next hop isn't resolved yet wait in the pending list of the node, next to an
+// incomplete arp entry. The arp request is retried with an exponential backoff, once the reply
+// comes the frames are sent, if it never comes they are dropped and their sources told so.
+
+const (
+	ARP_MAX_PENDING    = 16 // frames queued per unresolved next hop
+	ARP_MAX_TRIES      = 3
+	ARP_RETRY_INTERVAL = time.Millisecond * 100 // doubled after every try
+)
+
+type arpPending struct {
+	intf   *network.Interface
+	frames []*ethernetHeader
+	tries  int
+	timer  *time.Timer
+}
+
+var (
+	// protects the arp tables and the pending lists
+	arpLock        sync.Mutex
+	arpPendingList = map[*network.Node]map[[4]byte]*arpPending{}
+)
+
+// resolveNextHop returns the mac addr of the next hop when it is known, otherwise the frame is
+// held until the next hop is resolved, the first frame held for a next hop starts the resolution
+func resolveNextHop(node *network.Node, intf *network.Interface, nextHopIp *network.Ip, etherFrame *ethernetHeader) ([6]byte, bool) {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	if entry := arpTableLookup(network.GetNodeArpTable(node), nextHopIp); entry != nil && entry.State == network.ARP_COMPLETE {
+		entry.Updated = time.Now()
+		return entry.MacAddr.Addr, true
+	}
+
+	if arpPendingList[node] == nil {
+		arpPendingList[node] = map[[4]byte]*arpPending{}
+	}
+	if pending := arpPendingList[node][nextHopIp.Addr]; pending != nil {
+		if len(pending.frames) < ARP_MAX_PENDING {
+			pending.frames = append(pending.frames, etherFrame)
+		}
+		return [6]byte{}, false
+	}
+
+	pending := &arpPending{intf: intf, frames: []*ethernetHeader{etherFrame}}
+	arpPendingList[node][nextHopIp.Addr] = pending
+	addArpTableEntry(node, &network.ArpEntry{IpAddr: &network.Ip{Addr: nextHopIp.Addr},
+		MacAddr: &network.Mac{},
+		Name:    intf.Name,
+		State:   network.ARP_INCOMPLETE,
+		Updated: time.Now()})
+
+	retryArpRequest(node, nextHopIp.Addr, pending)
+	return [6]byte{}, false
+}
+
+// retryArpRequest must be called with arpLock held
+func retryArpRequest(node *network.Node, ip [4]byte, pending *arpPending) {
+	pending.tries++
+	go SendArpBroadcast(node, pending.intf, &network.Ip{Addr: ip})
+
+	backoff := ARP_RETRY_INTERVAL << (pending.tries - 1)
+	pending.timer = time.AfterFunc(backoff, func() {
+		arpLock.Lock()
+		if arpPendingList[node][ip] != pending {
+			arpLock.Unlock()
+			return
+		}
+		if pending.tries < ARP_MAX_TRIES {
+			retryArpRequest(node, ip, pending)
+			arpLock.Unlock()
+			return
+		}
+
+		delete(arpPendingList[node], ip)
+		deleteArpTableEntry(node, &network.Ip{Addr: ip})
+		arpLock.Unlock()
+
+		for _, frame := range pending.frames {
+			if frame.EtherType == ETH_IP {
+				if ipFrame, err := decodeIp(frame.Payload); err == nil {
+					sendIcmpError(node, ipFrame, ICMP_DEST_UNREACH, ICMP_HOST_UNREACH)
+				}
+			}
+		}
+	})
+}
+
+// flushArpPending sends the frames waiting for the mac addr of the ip that just got resolved
+func flushArpPending(node *network.Node, ip *network.Ip) {
+	arpLock.Lock()
+	pending := arpPendingList[node][ip.Addr]
+	entry := arpTableLookup(network.GetNodeArpTable(node), ip)
+	if pending == nil || entry == nil || entry.State != network.ARP_COMPLETE {
+		arpLock.Unlock()
+		return
+	}
+	pending.timer.Stop()
+	delete(arpPendingList[node], ip.Addr)
+	dstMac := entry.MacAddr.Addr
+	arpLock.Unlock()
+
+	for _, frame := range pending.frames {
+		frame.DstMacAddr = dstMac
+		frame.SrcMacAddr = network.GetIntfMac(pending.intf).Addr
+		sendPkt(frame, pending.intf)
+	}
+}
+
+// dropArpPendingIntf gives up on the resolutions going on through the interface
+func dropArpPendingIntf(node *network.Node, name string) {
+	arpLock.Lock()
+	defer arpLock.Unlock()
+
+	for ip, pending := range arpPendingList[node] {
+		if pending.intf.Name == name {
+			pending.timer.Stop()
+			delete(arpPendingList[node], ip)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package stack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools/pcapng"
+)
+
+// Frames are captured right where they leave (sendPkt) or enter (receivePkt) a node,
+// either for a single interface or for every interface of the topology at once.
+
+var (
+	captureLock   sync.Mutex
+	intfCaptures  = map[*network.Interface]*pcapng.Writer{}
+	globalCapture *pcapng.Writer
+)
+
+func StartIntfCapture(intf *network.Interface, path string) error {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	if _, ok := intfCaptures[intf]; ok {
+		return fmt.Errorf("Capture already running on interface: %s", intf.Att_node.Name+":"+intf.Name)
+	}
+	w, err := pcapng.Create(path)
+	if err != nil {
+		return err
+	}
+	intfCaptures[intf] = w
+	return nil
+}
+
+func StopIntfCapture(intf *network.Interface) error {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	w, ok := intfCaptures[intf]
+	if !ok {
+		return fmt.Errorf("No capture running on interface: %s", intf.Att_node.Name+":"+intf.Name)
+	}
+	delete(intfCaptures, intf)
+	return pcapng.Close(w)
+}
+
+func StartGlobalCapture(path string) error {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	if globalCapture != nil {
+		return fmt.Errorf("Topology capture already running")
+	}
+	w, err := pcapng.Create(path)
+	if err != nil {
+		return err
+	}
+	globalCapture = w
+	return nil
+}
+
+func StopGlobalCapture() error {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	if globalCapture == nil {
+		return fmt.Errorf("No topology capture running")
+	}
+	err := pcapng.Close(globalCapture)
+	globalCapture = nil
+	return err
+}
+
+// captureFrame records the ethernet frame, FCS excluded, on every capture interested in the interface
+func captureFrame(intf *network.Interface, frame []byte, outbound bool) {
+	captureLock.Lock()
+	w := intfCaptures[intf]
+	global := globalCapture
+	captureLock.Unlock()
+
+	if w == nil && global == nil {
+		return
+	}
+	if len(frame) > ETH_FCS_SIZE {
+		frame = frame[:len(frame)-ETH_FCS_SIZE]
+	}
+
+	now := time.Now()
+	name := intf.Att_node.Name + ":" + intf.Name
+	if w != nil {
+		pcapng.WriteFrame(w, name, now, frame, outbound)
+	}
+	if global != nil {
+		pcapng.WriteFrame(global, name, now, frame, outbound)
+	}
+}
@@ -1,16 +1,16 @@
 package stack
 
 import (
+	"errors"
 	"fmt"
 	"net"
-	"sync"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
-	"github.com/gkarthikreddi/tcp/tools"
 )
 
 const (
 	Reset  = "\033[0m"
+	Red    = "\033[31m"
 	Green  = "\033[32m"
 	Yellow = "\033[33m"
 	Blue   = "\033[34m"
@@ -37,34 +37,46 @@ func initUdpSocket(node *network.Node) error {
 }
 
 func InitNetworkListening(graph *network.Graph) {
-	nodes := []*network.Node{}
 	for curr := graph.List; curr != nil; curr = curr.Next {
-		initUdpSocket(curr)
-		nodes = append(nodes, curr)
+		if err := StartNodeListening(curr); err != nil {
+			fmt.Println(err)
+		}
 	}
+}
 
-	var wg sync.WaitGroup
-	for _, node := range nodes {
-		wg.Add(1)
-		go startListening(node, &wg)
+// StartNodeListening binds a udp port to the node and starts receiving frames on it
+func StartNodeListening(node *network.Node) error {
+	if err := initUdpSocket(node); err != nil {
+		return err
 	}
-	wg.Wait()
-}
 
-func startListening(node *network.Node, wg *sync.WaitGroup) error {
 	conn, err := net.ListenUDP("udp", network.GetNodeSocket(node))
 	if err != nil {
 		return fmt.Errorf("Error while establishing connection on node: %s with port: %d", node.Name, network.GetNodePort(node))
 	}
-	defer conn.Close()
+	network.AssignNodeConn(node, conn)
+
+	go startListening(node, conn)
+	return nil
+}
 
-	buffer := make([]byte, 1024)
-	wg.Done()
+// StopNodeListening closes the udp socket of the node which ends its receive loop
+func StopNodeListening(node *network.Node) {
+	if conn := network.GetNodeConn(node); conn != nil {
+		conn.Close()
+		network.AssignNodeConn(node, nil)
+	}
+}
+
+func startListening(node *network.Node, conn *net.UDPConn) {
+	buffer := make([]byte, MAX_PACKET_SIZE)
 	for {
 		if n, _, err := conn.ReadFromUDP(buffer); err == nil {
 			if err = receivePkt(node, buffer[:n]); err != nil {
 				fmt.Println(err)
 			}
+		} else if errors.Is(err, net.ErrClosed) {
+			return
 		} else {
 			fmt.Printf("Error while receiving data on node: %s on port: %d", node.Name, network.GetNodePort(node))
 		}
@@ -72,31 +84,66 @@ func startListening(node *network.Node, wg *sync.WaitGroup) error {
 }
 
 func sendPkt(etherFrame *ethernetHeader, intf *network.Interface) error {
+	// only the frames actually sent are mirrored
+	if network.IsIntfSvi(intf) {
+		if err := sviSendPkt(etherFrame, intf); err != nil {
+			return err
+		}
+		mirrorFrame(intf, etherFrame, network.MONITOR_TX)
+		return nil
+	}
+	if network.IsIntfChannel(intf) {
+		if err := channelSendPkt(etherFrame, intf); err != nil {
+			return err
+		}
+		mirrorFrame(intf, etherFrame, network.MONITOR_TX)
+		return nil
+	}
+	if !network.IsIntfUp(intf) {
+		return fmt.Errorf("Interface: %s is down", intf.Att_node.Name+":"+intf.Name)
+	}
+	if len(etherFrame.Payload) > network.GetIntfMtu(intf) {
+		return fmt.Errorf("Interface: %s can't send %d bytes, its mtu is %d", intf.Att_node.Name+":"+intf.Name, len(etherFrame.Payload), network.GetIntfMtu(intf))
+	}
+
 	dstNode, err := network.GetNbrNode(intf)
 	if err != nil {
 		return err
 	}
+	mirrorFrame(intf, etherFrame, network.MONITOR_TX)
 
 	dstIntf := network.GetNbrIntf(intf)
 	pkt := packet{Intf: dstIntf, EtherFrame: *etherFrame}
-	msg, err := tools.StructToByte(pkt)
+	msg, err := encodePacket(&pkt)
 	if err != nil {
 		return err
 	}
+	captureFrame(intf, msg[1+len(dstIntf):], true)
 	dstPort := network.GetNodePort(dstNode)
 	dstaddr := net.UDPAddr{Port: dstPort, IP: net.ParseIP("127.0.0.1")}
-	if conn, err := net.DialUDP("udp", nil, &dstaddr); err == nil {
-		conn.Write(msg)
-		conn.Close()
-	} else {
+	if err := transmit(intf, &dstaddr, msg); err != nil {
 		return fmt.Errorf("Can't estrablish connection with DestinationNode: %s, Port: %d", dstNode.Name, dstPort)
 	}
 	return nil
 }
 
+func writeUdp(dstaddr *net.UDPAddr, msg []byte) error {
+	conn, err := net.DialUDP("udp", nil, dstaddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(msg)
+	return err
+}
+
 func receivePkt(node *network.Node, data []byte) error {
-	if pkt, err := tools.ByteToStruct(data, packet{}); err == nil {
+	if pkt, err := decodePacket(data); err == nil {
 		if intf, err := network.GetIntfByIntfName(node, pkt.Intf); err == nil {
+			if network.IsIntfUp(intf) {
+				captureFrame(intf, data[1+len(pkt.Intf):], false)
+			}
 			layer2FrameRecieve(node, intf, &pkt.EtherFrame)
 			return nil
 		}
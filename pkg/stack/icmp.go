@@ -0,0 +1,239 @@
+package stack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+const ICMP_HDR_SIZE = 8
+
+type icmpHeader struct {
+	Type     uint8
+	Code     uint8
+	CheckSum uint16
+
+	// Echo messages carry an identifier and a sequence number here, error messages leave it unused
+	Identifier uint16
+	Sequence   uint16
+
+	Payload []byte
+}
+
+// icmpEvent is what a node learns from an ICMP message addressed to it
+type icmpEvent struct {
+	Type     uint8
+	Code     uint8
+	From     [4]byte
+	Sequence uint16
+	TTL      uint8
+	Size     int
+	Mtu      uint16 // of the next hop, for a fragmentation needed
+	Time     time.Time
+}
+
+// Nodes waiting for ICMP messages (ping), keyed by the echo identifier they used
+var (
+	icmpLock    sync.Mutex
+	icmpWaiters = map[uint16]chan icmpEvent{}
+	icmpNextId  = uint16(time.Now().UnixNano())
+)
+
+func encodeIcmp(icmp *icmpHeader) []byte {
+	buf := make([]byte, 0, ICMP_HDR_SIZE+len(icmp.Payload))
+	buf = append(buf, icmp.Type, icmp.Code, 0, 0)
+	buf = binary.BigEndian.AppendUint16(buf, icmp.Identifier)
+	buf = binary.BigEndian.AppendUint16(buf, icmp.Sequence)
+	buf = append(buf, icmp.Payload...)
+
+	icmp.CheckSum = inetChecksum(buf)
+	binary.BigEndian.PutUint16(buf[2:], icmp.CheckSum)
+	return buf
+}
+
+func decodeIcmp(data []byte) (*icmpHeader, error) {
+	if len(data) < ICMP_HDR_SIZE {
+		return nil, fmt.Errorf("ICMP message too short: %d bytes", len(data))
+	}
+	if inetChecksum(data) != 0 {
+		return nil, fmt.Errorf("ICMP message with bad checksum")
+	}
+
+	return &icmpHeader{
+		Type:       data[0],
+		Code:       data[1],
+		CheckSum:   binary.BigEndian.Uint16(data[2:]),
+		Identifier: binary.BigEndian.Uint16(data[4:]),
+		Sequence:   binary.BigEndian.Uint16(data[6:]),
+		Payload:    data[ICMP_HDR_SIZE:],
+	}, nil
+}
+
+func registerIcmpWaiter() (uint16, chan icmpEvent) {
+	icmpLock.Lock()
+	defer icmpLock.Unlock()
+
+	icmpNextId++
+	ch := make(chan icmpEvent, 16)
+	icmpWaiters[icmpNextId] = ch
+	return icmpNextId, ch
+}
+
+func unregisterIcmpWaiter(id uint16) {
+	icmpLock.Lock()
+	defer icmpLock.Unlock()
+	delete(icmpWaiters, id)
+}
+
+func notifyIcmpWaiter(id uint16, event icmpEvent) {
+	icmpLock.Lock()
+	ch := icmpWaiters[id]
+	icmpLock.Unlock()
+
+	if ch != nil {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func processIcmp(node *network.Node, ipFrame *ipHeader) error {
+	icmp, err := decodeIcmp(ipFrame.Payload)
+	if err != nil {
+		return err
+	}
+
+	switch icmp.Type {
+	case ICMP_ECHO_REQ:
+		reply := icmpHeader{Type: ICMP_ECHO_REP,
+			Identifier: icmp.Identifier,
+			Sequence:   icmp.Sequence,
+			Payload:    icmp.Payload,
+		}
+		src := ipFrame.DstIpAddr
+		return demotePktToLayer3(node, &src, &network.Ip{Addr: ipFrame.SrcIpAddr}, ICMP_PRO, encodeIcmp(&reply))
+	case ICMP_ECHO_REP:
+		notifyIcmpWaiter(icmp.Identifier, icmpEvent{Type: icmp.Type,
+			From:     ipFrame.SrcIpAddr,
+			Sequence: icmp.Sequence,
+			TTL:      ipFrame.TTL,
+			Size:     len(ipFrame.Payload),
+			Time:     time.Now(),
+		})
+	case ICMP_TIME_EXCEEDED, ICMP_DEST_UNREACH:
+		// the error quotes the header of our echo request, followed by the first 8 bytes of its data
+		if orig, err := decodeQuotedIp(icmp.Payload); err == nil && orig.Protocol == ICMP_PRO && len(orig.Payload) >= ICMP_HDR_SIZE {
+			notifyIcmpWaiter(binary.BigEndian.Uint16(orig.Payload[4:]), icmpEvent{Type: icmp.Type,
+				Code:     icmp.Code,
+				From:     ipFrame.SrcIpAddr,
+				Sequence: binary.BigEndian.Uint16(orig.Payload[6:]),
+				TTL:      ipFrame.TTL,
+				Mtu:      icmp.Sequence,
+				Time:     time.Now(),
+			})
+		}
+	}
+	return nil
+}
+
+// sendIcmpError reports the packet that couldn't be delivered back to its source (RFC 792)
+func sendIcmpError(node *network.Node, ipFrame *ipHeader, icmpType uint8, code uint8) error {
+	return sendIcmpErrorMsg(node, ipFrame, icmpHeader{Type: icmpType, Code: code})
+}
+
+// sendIcmpFragNeeded reports a packet with the DF flag too large for the next hop, the low
+// half of the unused word carries the mtu of the next hop (RFC 1191)
+func sendIcmpFragNeeded(node *network.Node, ipFrame *ipHeader, mtu int) error {
+	return sendIcmpErrorMsg(node, ipFrame, icmpHeader{Type: ICMP_DEST_UNREACH, Code: ICMP_FRAG_NEEDED, Sequence: uint16(mtu)})
+}
+
+func sendIcmpErrorMsg(node *network.Node, ipFrame *ipHeader, msg icmpHeader) error {
+	// never answer an error with another error, nor a fragment other than the first one
+	if ipFrame.Protocol == ICMP_PRO && len(ipFrame.Payload) > 0 && isIcmpError(ipFrame.Payload[0]) || ipFrame.FragOffset != 0 {
+		return nil
+	}
+
+	quote := encodeIp(ipFrame)
+	msg.Payload = quote[:min(len(quote), IP_HDR_MIN_SIZE+8)]
+	return demotePktToLayer3(node, nil, &network.Ip{Addr: ipFrame.SrcIpAddr}, ICMP_PRO, encodeIcmp(&msg))
+}
+
+func isIcmpError(icmpType uint8) bool {
+	return icmpType == ICMP_TIME_EXCEEDED || icmpType == ICMP_DEST_UNREACH
+}
+
+// unreachableCode maps the errors of the forwarding path to the code of a destination unreachable
+func unreachableCode(err error) (uint8, bool) {
+	switch {
+	case errors.Is(err, errNetUnreachable):
+		return ICMP_NET_UNREACH, true
+	case errors.Is(err, errHostUnreachable):
+		return ICMP_HOST_UNREACH, true
+	case errors.Is(err, errProtoUnreachable):
+		return ICMP_PROTO_UNREACH, true
+	case errors.Is(err, errFragNeeded):
+		return ICMP_FRAG_NEEDED, true
+	}
+	return 0, false
+}
+
+func icmpErrorStr(icmpType uint8, code uint8) string {
+	switch {
+	case icmpType == ICMP_TIME_EXCEEDED && code == ICMP_FRAG_EXCEEDED:
+		return "Fragment reassembly time exceeded"
+	case icmpType == ICMP_TIME_EXCEEDED:
+		return "Time to live exceeded"
+	case icmpType != ICMP_DEST_UNREACH:
+	case code == ICMP_NET_UNREACH:
+		return errNetUnreachable.Error()
+	case code == ICMP_HOST_UNREACH:
+		return errHostUnreachable.Error()
+	case code == ICMP_PROTO_UNREACH:
+		return errProtoUnreachable.Error()
+	case code == ICMP_FRAG_NEEDED:
+		return errFragNeeded.Error()
+	}
+	return fmt.Sprintf("ICMP type %d code %d", icmpType, code)
+}
+
+// icmpErrorMark is the traceroute annotation of an unreachable destination
+func icmpErrorMark(icmpType uint8, code uint8) string {
+	if icmpType != ICMP_DEST_UNREACH {
+		return ""
+	}
+	switch code {
+	case ICMP_NET_UNREACH:
+		return "!N"
+	case ICMP_HOST_UNREACH:
+		return "!H"
+	case ICMP_PROTO_UNREACH:
+		return "!P"
+	case ICMP_FRAG_NEEDED:
+		return "!F"
+	}
+	return fmt.Sprintf("!<%d>", code)
+}
+
+// decodeQuotedIp parses the truncated datagram carried by an ICMP error, the checksum
+// and length checks of decodeIp don't hold for it
+func decodeQuotedIp(data []byte) (*ipHeader, error) {
+	if len(data) < IP_HDR_MIN_SIZE || int(data[0]&0x0f)*4 < IP_HDR_MIN_SIZE || int(data[0]&0x0f)*4 > len(data) {
+		return nil, fmt.Errorf("Malformed IP header in ICMP error")
+	}
+
+	ip := &ipHeader{
+		Version:  data[0] >> 4,
+		IHL:      data[0] & 0x0f,
+		TTL:      data[8],
+		Protocol: data[9],
+		Payload:  data[int(data[0]&0x0f)*4:],
+	}
+	copy(ip.SrcIpAddr[:], data[12:16])
+	copy(ip.DstIpAddr[:], data[16:20])
+	return ip, nil
+}
@@ -0,0 +1,104 @@
+package stack
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Frames crossing an impaired link go through here, all the randomness comes from a single
+// generator so a run can be reproduced by reusing its seed.
+
+var (
+	rngLock sync.Mutex
+	rng     = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// when the transmitter of an interface is free again, used to enforce bandwidth caps
+	txLock      sync.Mutex
+	txBusyUntil = map[*network.Interface]time.Time{}
+)
+
+func SetImpairmentSeed(seed int64) {
+	rngLock.Lock()
+	defer rngLock.Unlock()
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// chance returns true with the given probability in percent
+func chance(pct float64) bool {
+	if pct <= 0 {
+		return false
+	}
+	rngLock.Lock()
+	defer rngLock.Unlock()
+	return rng.Float64()*100 < pct
+}
+
+func jitter(impair *network.Impairment) time.Duration {
+	if impair.Jitter <= 0 {
+		return 0
+	}
+
+	rngLock.Lock()
+	defer rngLock.Unlock()
+	if impair.JitterDist == network.NORMAL {
+		return time.Duration(rng.NormFloat64() * float64(impair.Jitter))
+	}
+	return time.Duration((rng.Float64()*2 - 1) * float64(impair.Jitter))
+}
+
+// serializationDelay books the transmitter of the interface for the frame and returns
+// how long the frame waits until it has completely left the interface
+func serializationDelay(intf *network.Interface, impair *network.Impairment, size int) time.Duration {
+	if impair.Bandwidth == 0 {
+		return 0
+	}
+
+	txLock.Lock()
+	defer txLock.Unlock()
+
+	now := time.Now()
+	start := txBusyUntil[intf]
+	if start.Before(now) {
+		start = now
+	}
+	end := start.Add(time.Duration(uint64(size*8) * uint64(time.Second) / impair.Bandwidth))
+	txBusyUntil[intf] = end
+
+	return end.Sub(now)
+}
+
+func transmit(intf *network.Interface, dstaddr *net.UDPAddr, msg []byte) error {
+	impair := network.GetLinkImpairment(intf)
+	if !network.IsLinkImpaired(impair) {
+		return writeUdp(dstaddr, msg)
+	}
+
+	if chance(impair.Loss) {
+		return nil
+	}
+
+	delay := serializationDelay(intf, impair, len(msg)) + impair.Delay + jitter(impair)
+	if chance(impair.Reorder) {
+		delay += max(impair.Delay+impair.Jitter, time.Millisecond*10)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	copies := 1
+	if chance(impair.Duplicate) {
+		copies = 2
+	}
+	for i := 0; i < copies; i++ {
+		if delay == 0 {
+			writeUdp(dstaddr, msg)
+		} else {
+			time.AfterFunc(delay, func() { writeUdp(dstaddr, msg) })
+		}
+	}
+	return nil
+}
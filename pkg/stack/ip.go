@@ -1,10 +1,33 @@
 package stack
 
+import "errors"
+
 const (
 	ETH_IP        = 0x0800
 	ICMP_PRO      = 1
+	TCP_PRO       = 6
+	UDP_PRO       = 17
 	ICMP_ECHO_REQ = 8
 	ICMP_ECHO_REP = 0
+
+	ICMP_DEST_UNREACH  = 3
+	ICMP_TIME_EXCEEDED = 11
+	ICMP_TTL_EXCEEDED  = 0 // code of a time exceeded in transit
+	ICMP_FRAG_EXCEEDED = 1 // code of a time exceeded during reassembly
+
+	// codes of a destination unreachable
+	ICMP_NET_UNREACH   = 0
+	ICMP_HOST_UNREACH  = 1
+	ICMP_PROTO_UNREACH = 2
+	ICMP_FRAG_NEEDED   = 4
+)
+
+// Errors of packets that can't be delivered, reported to the source with a destination unreachable
+var (
+	errNetUnreachable   = errors.New("Destination Net Unreachable")
+	errHostUnreachable  = errors.New("Destination Host Unreachable")
+	errProtoUnreachable = errors.New("Destination Protocol Unreachable")
+	errFragNeeded       = errors.New("Fragmentation needed and DF set")
 )
 
 type ipHeader struct {
@@ -13,25 +36,27 @@ type ipHeader struct {
 	TOS         uint8
 	TotalLength uint16
 
-	// Fragmentation related members, we don't use these
+	// Fragmentation related members, the fragments of a packet share its Identification
 	Identification uint16
 	UnusedFlag     bool
-	DfFlag         bool
-	MoreFlag       bool
-	FragOffset     uint16
+	DfFlag         bool   // routers drop the packet rather than fragment it
+	MoreFlag       bool   // more fragments follow this one
+	FragOffset     uint16 // in units of 8 bytes
 
 	TTL       uint8
 	Protocol  uint8
 	CheckSum  uint16
 	SrcIpAddr [4]byte
 	DstIpAddr [4]byte
+
+	Payload []byte // not part of the header, carried right after it on the wire
 }
 
 func newIpHeader() ipHeader {
 	return ipHeader{
-		Version: 4,
-		IHL:     5, // We will not be using option field, hence hdr size shall always be 5*4 = 20B
-		DfFlag:  true,
-		TTL:     64,
+		Version:        4,
+		IHL:            5, // We will not be using option field, hence hdr size shall always be 5*4 = 20B
+		Identification: nextIpId(),
+		TTL:            64,
 	}
 }
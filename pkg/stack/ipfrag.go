@@ -0,0 +1,196 @@
+package stack
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// A packet larger than the mtu of the outgoing interface is split into fragments (RFC 791)
+// unless it has the DF flag, the destination puts them back together. Fragments still
+// missing once IP_REASM_TIMEOUT has passed since the first one arrived void the packet.
+
+const (
+	IP_REASM_TIMEOUT = time.Second * 30
+	IP_REASM_TICK    = time.Second
+)
+
+// fragKey identifies the fragments of one packet on a node
+type fragKey struct {
+	node     *network.Node
+	src, dst [4]byte
+	protocol uint8
+	id       uint16
+}
+
+type fragPiece struct {
+	offset int // in bytes
+	data   []byte
+}
+
+type fragBuffer struct {
+	first   *ipHeader // the fragment at offset 0, its header becomes the one of the packet
+	pieces  []fragPiece
+	size    int // length of the payload, known once the last fragment arrived, -1 until then
+	started time.Time
+}
+
+var (
+	ipId      atomic.Uint32
+	fragLock  sync.Mutex
+	fragTable = map[fragKey]*fragBuffer{}
+)
+
+func InitIpReassembly() {
+	go func() {
+		for range time.Tick(IP_REASM_TICK) {
+			expireFragments()
+		}
+	}()
+}
+
+// nextIpId is the Identification of the next packet originated, shared by its fragments
+func nextIpId() uint16 {
+	return uint16(ipId.Add(1))
+}
+
+// ipEgressMtu is the mtu of the interface the packet leaves through, a packet delivered
+// locally is never fragmented
+func ipEgressMtu(node *network.Node, nextHopIp *network.Ip, outIntf string) int {
+	var intf *network.Interface
+	var err error
+	if outIntf != "NA" {
+		intf, err = network.GetIntfByIntfName(node, outIntf)
+	} else if !isLocalDelivery(node, nextHopIp) {
+		intf, err = network.NodeGetMatchingSubnet(node, nextHopIp)
+	}
+	if intf == nil || err != nil {
+		return MAX_PACKET_SIZE
+	}
+	return network.GetIntfMtu(intf)
+}
+
+// dstEgressMtu is the mtu of the interface the packets the node originates to dst leave through
+func dstEgressMtu(node *network.Node, dst [4]byte) int {
+	route := nodeRouteLookup(node, dst)
+	if route == nil {
+		return MAX_PACKET_SIZE
+	}
+	nextHopIp := route.GatewayIp
+	if isDirectRoute(route) {
+		nextHopIp = &network.Ip{Addr: dst}
+	}
+	return ipEgressMtu(node, nextHopIp, route.OutIntf)
+}
+
+// fragmentIp splits the packet into fragments fitting the mtu, every fragment but the last
+// carries a multiple of 8 bytes. Fragmenting a fragment keeps its place in the original packet.
+func fragmentIp(ipFrame *ipHeader, mtu int) []*ipHeader {
+	step := (mtu - IP_HDR_MIN_SIZE) &^ 7
+	var frags []*ipHeader
+	for off := 0; off < len(ipFrame.Payload); off += step {
+		end := min(off+step, len(ipFrame.Payload))
+		frag := *ipFrame
+		frag.Payload = ipFrame.Payload[off:end]
+		frag.FragOffset = ipFrame.FragOffset + uint16(off/8)
+		frag.MoreFlag = end < len(ipFrame.Payload) || ipFrame.MoreFlag
+		frags = append(frags, &frag)
+	}
+	return frags
+}
+
+func isIpFragment(ipFrame *ipHeader) bool {
+	return ipFrame.MoreFlag || ipFrame.FragOffset != 0
+}
+
+// reassembleIp stores the fragment, the whole packet is returned once its last missing
+// fragment arrived
+func reassembleIp(node *network.Node, ipFrame *ipHeader) *ipHeader {
+	key := fragKey{node: node,
+		src:      ipFrame.SrcIpAddr,
+		dst:      ipFrame.DstIpAddr,
+		protocol: ipFrame.Protocol,
+		id:       ipFrame.Identification}
+	offset := int(ipFrame.FragOffset) * 8
+	// the payload points into the receive buffer of the node, it is reused by the next frame
+	data := append([]byte(nil), ipFrame.Payload...)
+
+	fragLock.Lock()
+	defer fragLock.Unlock()
+	buf := fragTable[key]
+	if buf == nil {
+		buf = &fragBuffer{size: -1, started: time.Now()}
+		fragTable[key] = buf
+	}
+	if offset == 0 {
+		first := *ipFrame
+		first.Payload = data
+		buf.first = &first
+	}
+	if !ipFrame.MoreFlag {
+		buf.size = offset + len(data)
+	}
+	buf.pieces = append(buf.pieces, fragPiece{offset: offset, data: data})
+	if buf.size > MAX_PACKET_SIZE-IP_HDR_MIN_SIZE {
+		delete(fragTable, key)
+		return nil
+	}
+	if buf.first == nil || buf.size < 0 {
+		return nil
+	}
+
+	// the pieces may overlap, the packet is complete once they cover it without a hole
+	sort.Slice(buf.pieces, func(i, j int) bool { return buf.pieces[i].offset < buf.pieces[j].offset })
+	covered := 0
+	for _, piece := range buf.pieces {
+		if piece.offset > covered {
+			return nil
+		}
+		covered = max(covered, piece.offset+len(piece.data))
+	}
+	if covered < buf.size {
+		return nil
+	}
+
+	payload := make([]byte, buf.size)
+	for _, piece := range buf.pieces {
+		if piece.offset < buf.size {
+			copy(payload[piece.offset:], piece.data)
+		}
+	}
+	delete(fragTable, key)
+
+	whole := *buf.first
+	whole.MoreFlag, whole.FragOffset = false, 0
+	whole.Payload = payload
+	return &whole
+}
+
+// expireFragments drops the packets not reassembled in time, their source is told when
+// the first fragment was received (RFC 792)
+func expireFragments() {
+	type expired struct {
+		node  *network.Node
+		first *ipHeader
+	}
+	var expires []expired
+
+	fragLock.Lock()
+	for key, buf := range fragTable {
+		if time.Since(buf.started) < IP_REASM_TIMEOUT {
+			continue
+		}
+		delete(fragTable, key)
+		if buf.first != nil {
+			expires = append(expires, expired{node: key.node, first: buf.first})
+		}
+	}
+	fragLock.Unlock()
+
+	for _, val := range expires {
+		sendIcmpError(val.node, val.first, ICMP_TIME_EXCEEDED, ICMP_FRAG_EXCEEDED)
+	}
+}
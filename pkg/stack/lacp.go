@@ -0,0 +1,269 @@
+package stack
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools"
+)
+
+// Port-channel members in active or passive mode negotiate the bundle with LACP (802.1AX),
+// a member carries frames once both ends agreed on it. Lacpdus are exchanged at the fast rate.
+
+const (
+	LACP_FAST_PERIODIC = time.Second
+	LACP_TIMEOUT       = LACP_FAST_PERIODIC * 3 // partner info not refreshed for that long is discarded
+	LACP_TICK          = time.Millisecond * 100
+	LACP_SYS_PRIORITY  = 32768
+	LACP_PORT_PRIORITY = 32768
+
+	ETH_SLOW_PROTOCOLS = 0x8809
+	LACP_SUBTYPE       = 1
+	LACP_VERSION       = 1
+
+	LACP_STATE_ACTIVITY     = 0x01
+	LACP_STATE_TIMEOUT      = 0x02 // short timeout
+	LACP_STATE_AGGREGATION  = 0x04
+	LACP_STATE_SYNC         = 0x08
+	LACP_STATE_COLLECTING   = 0x10
+	LACP_STATE_DISTRIBUTING = 0x20
+	LACP_STATE_DEFAULTED    = 0x40
+)
+
+var lacpMulticastMac = [6]byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x02}
+
+type lacpInfo struct {
+	SysPriority  uint16
+	System       [6]byte
+	Key          uint16
+	PortPriority uint16
+	Port         uint16
+	State        uint8
+}
+
+type lacpHeader struct {
+	Subtype           uint8
+	Version           uint8
+	Actor             lacpInfo
+	Partner           lacpInfo
+	CollectorMaxDelay uint16
+}
+
+// lacpPort is the negotiation state of a member
+type lacpPort struct {
+	partner  lacpInfo
+	heardAt  time.Time
+	heard    bool
+	selected bool // the member leads to the same partner as the rest of the bundle
+	state    uint8
+	lastTx   time.Time
+}
+
+var (
+	lacpLock  sync.Mutex
+	lacpPorts = map[*network.Interface]*lacpPort{}
+)
+
+func InitLinkAggregation(graph *network.Graph) {
+	go func() {
+		for range time.Tick(LACP_TICK) {
+			for node := graph.List; node != nil; node = node.Next {
+				lacpTick(node)
+			}
+		}
+	}()
+}
+
+func lacpTick(node *network.Node) {
+	lacpLock.Lock()
+	var txs []*network.Interface
+	var frames []*ethernetHeader
+	for i, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		channel := network.GetIntfChannel(intf)
+		if channel == nil {
+			delete(lacpPorts, intf)
+			continue
+		}
+
+		mode := network.GetIntfChannelMode(intf)
+		if mode == network.CHANNEL_ON {
+			delete(lacpPorts, intf)
+			network.SetIntfBundled(intf, network.IsIntfUp(intf))
+			continue
+		}
+
+		port := lacpPorts[intf]
+		if port == nil {
+			port = &lacpPort{}
+			lacpPorts[intf] = port
+		}
+		if !network.IsIntfUp(intf) || port.heard && time.Since(port.heardAt) > LACP_TIMEOUT {
+			port.heard, port.partner = false, lacpInfo{}
+		}
+
+		port.selected = port.heard && lacpSamePartner(channel, intf, port)
+		bundled := port.selected && port.partner.State&LACP_STATE_SYNC != 0
+		network.SetIntfBundled(intf, bundled)
+
+		state := uint8(LACP_STATE_TIMEOUT | LACP_STATE_AGGREGATION)
+		if mode == network.CHANNEL_ACTIVE {
+			state |= LACP_STATE_ACTIVITY
+		}
+		if port.selected {
+			state |= LACP_STATE_SYNC
+		}
+		if bundled {
+			state |= LACP_STATE_COLLECTING | LACP_STATE_DISTRIBUTING
+		}
+		if !port.heard {
+			state |= LACP_STATE_DEFAULTED
+		}
+
+		// a passive member only talks to a partner that spoke first
+		if !network.IsIntfUp(intf) || mode == network.CHANNEL_PASSIVE && !port.heard {
+			port.state = state
+			continue
+		}
+		if state != port.state || time.Since(port.lastTx) >= LACP_FAST_PERIODIC {
+			port.state, port.lastTx = state, time.Now()
+			lacp := lacpHeader{Subtype: LACP_SUBTYPE,
+				Version: LACP_VERSION,
+				Actor: lacpInfo{SysPriority: LACP_SYS_PRIORITY,
+					System:       network.GetNodeSystemMac(node).Addr,
+					Key:          network.GetIntfChannelId(channel),
+					PortPriority: LACP_PORT_PRIORITY,
+					Port:         uint16(i + 1),
+					State:        state},
+				Partner: port.partner}
+			txs = append(txs, intf)
+			frames = append(frames, &ethernetHeader{DstMacAddr: lacpMulticastMac,
+				SrcMacAddr: network.GetIntfMac(intf).Addr,
+				EtherType:  ETH_SLOW_PROTOCOLS,
+				Payload:    encodeLacp(&lacp)})
+		}
+	}
+	lacpLock.Unlock()
+
+	for i, intf := range txs {
+		sendPkt(frames[i], intf)
+	}
+}
+
+// lacpSamePartner tells whether the member leads to the same system and key as the other
+// selected members of the port-channel, a member cabled elsewhere stays out of the bundle
+func lacpSamePartner(channel *network.Interface, intf *network.Interface, port *lacpPort) bool {
+	for _, member := range network.GetChannelMembers(channel) {
+		if member == intf {
+			continue
+		}
+		if other := lacpPorts[member]; other != nil && other.selected {
+			return other.partner.System == port.partner.System && other.partner.Key == port.partner.Key
+		}
+	}
+	return true
+}
+
+func isLacpdu(etherFrame *ethernetHeader) bool {
+	return etherFrame.DstMacAddr == lacpMulticastMac && etherFrame.EtherType == ETH_SLOW_PROTOCOLS
+}
+
+// processLacpdu records what the partner said about itself, the next tick answers it.
+// Lacpdus received on a port that isn't negotiating a bundle are dropped.
+func processLacpdu(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) {
+	lacp, err := decodeLacp(etherFrame.Payload)
+	if err != nil {
+		return
+	}
+
+	lacpLock.Lock()
+	defer lacpLock.Unlock()
+	if network.GetIntfChannel(intf) == nil || network.GetIntfChannelMode(intf) == network.CHANNEL_ON {
+		return
+	}
+	port := lacpPorts[intf]
+	if port == nil {
+		port = &lacpPort{}
+		lacpPorts[intf] = port
+	}
+	port.partner, port.heard, port.heardAt = lacp.Actor, true, time.Now()
+}
+
+// channelSendPkt sends the frame on one of the bundled members, all the frames of a flow
+// take the same member so that they stay in order
+func channelSendPkt(etherFrame *ethernetHeader, channel *network.Interface) error {
+	var members []*network.Interface
+	for _, member := range network.GetChannelMembers(channel) {
+		if network.IsIntfBundled(member) && network.IsIntfUp(member) {
+			members = append(members, member)
+		}
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("Interface: %s is down", channel.Att_node.Name+":"+channel.Name)
+	}
+
+	return sendPkt(etherFrame, members[flowHash(etherFrame)%uint32(len(members))])
+}
+
+// flowHash hashes the mac addrs, the ip addrs and the tcp/udp ports of the frame
+func flowHash(etherFrame *ethernetHeader) uint32 {
+	h := fnv.New32a()
+	h.Write(etherFrame.SrcMacAddr[:])
+	h.Write(etherFrame.DstMacAddr[:])
+
+	payload := etherFrame.Payload
+	if etherFrame.EtherType == ETH_IP && len(payload) >= IP_HDR_MIN_SIZE {
+		h.Write(payload[12:20])
+
+		hdrLen := int(payload[0]&0x0f) * 4
+		fragOff := (uint16(payload[6])<<8 | uint16(payload[7])) & IP_FRAG_OFF_MASK
+		if (payload[9] == TCP_PRO || payload[9] == UDP_PRO) && fragOff == 0 && len(payload) >= hdrLen+4 {
+			h.Write(payload[hdrLen : hdrLen+4])
+		}
+	}
+	return h.Sum32()
+}
+
+// SetIntfChannelGroup moves the interface into port-channel id, what was learned on the
+// interface itself is forgotten
+func SetIntfChannelGroup(node *network.Node, name string, id uint16, mode network.ChannelMode) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	flushIntfNeighbors(node, name)
+	lacpLock.Lock()
+	defer lacpLock.Unlock()
+	delete(lacpPorts, intf)
+	return network.NodeSetIntfChannelGroup(node, name, id, mode)
+}
+
+func UnsetIntfChannelGroup(node *network.Node, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	lacpLock.Lock()
+	defer lacpLock.Unlock()
+	delete(lacpPorts, intf)
+	return network.NodeUnsetIntfChannelGroup(node, name)
+}
+
+// GetLacpPartner returns the system id of the partner heard on the member, if any
+func GetLacpPartner(intf *network.Interface) (string, bool) {
+	lacpLock.Lock()
+	defer lacpLock.Unlock()
+
+	port := lacpPorts[intf]
+	if port == nil || !port.heard {
+		return "", false
+	}
+	return fmt.Sprintf("%d.%s", port.partner.SysPriority, tools.ConvertAddrToStr(port.partner.System[:])), true
+}
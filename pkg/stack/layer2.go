@@ -1,11 +1,7 @@
 package stack
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/gkarthikreddi/tcp/pkg/network"
-	"github.com/gkarthikreddi/tcp/tools"
 )
 
 type ethernetHeader struct {
@@ -13,17 +9,13 @@ type ethernetHeader struct {
 	SrcMacAddr [6]byte
 	Tagged     *vlan8021qHeader
 	EtherType  uint16
-	Payload    [500]byte // Ideally it could be between 45 - 1500 bytes
+	Payload    []byte // between 46 and 1500 bytes on the wire, shorter payloads get padded
 	Fcs        uint32
 }
 
 func assignPayload(etherFrame *ethernetHeader, arpFrame *arpHeader) error {
-	if msg, err := tools.StructToByte(arpFrame); err == nil {
-		copy(etherFrame.Payload[:], msg)
-		return nil
-	} else {
-		return err
-	}
+	etherFrame.Payload = encodeArp(arpFrame)
+	return nil
 }
 
 func fillBroadcastAddr(mac *[6]byte) {
@@ -44,6 +36,11 @@ func validL2Intf(intf *network.Interface, ether *ethernetHeader) bool {
 		return false
 	}
 
+	// vlan 0 and 4095 are reserved, such tags are never switched
+	if ether.Tagged != nil && !network.IsValidVlan(ether.Tagged.Id) {
+		return false
+	}
+
 	mode := network.GetIntfL2Mode(intf)
 	if mode == network.ACCESS {
 		vlan := network.GetIntfVlanMembership(intf)[0]
@@ -53,29 +50,57 @@ func validL2Intf(intf *network.Interface, ether *ethernetHeader) bool {
 			}
 			return false
 		} else {
+			// an access port without a vlan switches untagged frames as they are
 			if vlan != 0 {
-				ether.Tagged = &vlan8021qHeader{TPID: 0x800, Id: vlan}
-				return true
+				ether.Tagged = &vlan8021qHeader{TPID: VLAN_TPID, Id: vlan}
 			}
-			return false
+			return true
 		}
 	} else if mode == network.TRUNK {
-		if ether.Tagged != nil {
-			for _, val := range network.GetIntfVlanMembership(intf) {
-				if val == 0 {
-					break
-				}
-				if val == ether.Tagged.Id {
-					return true
-				}
+		if ether.Tagged == nil {
+			// untagged frames belong to the native vlan, they are dropped when there is none
+			native := network.GetIntfNativeVlan(intf)
+			if native == 0 {
+				return false
 			}
+			ether.Tagged = &vlan8021qHeader{TPID: VLAN_TPID, Id: native}
+			return true
 		}
-		return false
+		return network.IsIntfVlanMember(intf, ether.Tagged.Id)
 	}
 	return false
 }
 
 func layer2FrameRecieve(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) {
+	// frames still in flight when the link went down are lost, and so are giants larger than the mtu
+	if !network.IsIntfUp(intf) || len(etherFrame.Payload) > network.GetIntfMtu(intf) {
+		return
+	}
+	mirrorFrame(intf, etherFrame, network.MONITOR_RX)
+	// a monitor destination only sends the copies
+	if network.IsIntfMonitorDest(intf) {
+		return
+	}
+	if isLldpdu(etherFrame) {
+		processLldpdu(node, intf, etherFrame)
+		return
+	}
+	if isLacpdu(etherFrame) {
+		processLacpdu(node, intf, etherFrame)
+		return
+	}
+	// frames of a bundle are handled as received on the port-channel
+	if channel := network.GetIntfChannel(intf); channel != nil {
+		if !network.IsIntfBundled(intf) || !network.IsIntfUp(channel) {
+			return
+		}
+		intf = channel
+		mirrorFrame(intf, etherFrame, network.MONITOR_RX)
+	}
+	if !network.IsIntfIp(intf) && isBpdu(etherFrame) {
+		processBpdu(node, intf, etherFrame)
+		return
+	}
 	if !validL2Intf(intf, etherFrame) {
 		return
 	}
@@ -90,7 +115,7 @@ func layer2FrameRecieve(node *network.Node, intf *network.Interface, etherFrame
 func promotePktToLayer2(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) {
 	switch etherFrame.EtherType {
 	case ARP_MSG:
-		if arpFrame, err := tools.ByteToStruct(etherFrame.Payload[:], arpHeader{}); err == nil {
+		if arpFrame, err := decodeArp(etherFrame.Payload); err == nil {
 			switch arpFrame.Operation {
 			case ARP_BROAD_REQ:
 				processArpBroadcast(node, intf, etherFrame)
@@ -109,12 +134,18 @@ func promotePktToLayer2(node *network.Node, intf *network.Interface, etherFrame
 
 func demotePktToLayer2(node *network.Node, nextHopIp *network.Ip, outIntf string, ipFrame *ipHeader, protocol uint16) error {
 	if protocol == ETH_IP {
-		etherFrame := &ethernetHeader{EtherType: ETH_IP}
-		if msg, err := tools.StructToByte(ipFrame); err == nil {
-			copy(etherFrame.Payload[:], msg)
-			return l2ForwardIpPkt(node, nextHopIp, outIntf, etherFrame)
-		} else {
-			return fmt.Errorf("Can't assign IP payload into EtherFrame")
+		mtu := ipEgressMtu(node, nextHopIp, outIntf)
+		if IP_HDR_MIN_SIZE+len(ipFrame.Payload) <= mtu {
+			return l2ForwardIpPkt(node, nextHopIp, outIntf, &ethernetHeader{EtherType: ETH_IP, Payload: encodeIp(ipFrame)})
+		}
+		if ipFrame.DfFlag {
+			return errFragNeeded
+		}
+		for _, frag := range fragmentIp(ipFrame, mtu) {
+			etherFrame := &ethernetHeader{EtherType: ETH_IP, Payload: encodeIp(frag)}
+			if err := l2ForwardIpPkt(node, nextHopIp, outIntf, etherFrame); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -133,28 +164,16 @@ func l2ForwardIpPkt(node *network.Node, nextHopIp *network.Ip, outIntf string, e
 			return nil
 		} else {
 			if intf, err = network.NodeGetMatchingSubnet(node, nextHopIp); err != nil {
-				return err
+				return errHostUnreachable
 			}
 		}
 	}
 
-	entry := arpTableLookup(network.GetNodeArpTable(node), nextHopIp)
-	if entry == nil {
-		go SendArpBroadcast(node, intf, nextHopIp)
-		time.Sleep(time.Millisecond * 100)
-		entry = arpTableLookup(network.GetNodeArpTable(node), nextHopIp)
-		if entry == nil {
-			fmt.Println("quit")
-			return nil
-		}
+	dstMac, ok := resolveNextHop(node, intf, nextHopIp, etherFrame)
+	if !ok {
+		return nil
 	}
-	// if entry != nil {
-	etherFrame.DstMacAddr = entry.MacAddr.Addr
+	etherFrame.DstMacAddr = dstMac
 	etherFrame.SrcMacAddr = network.GetIntfMac(intf).Addr
-	sendPkt(etherFrame, intf)
-	// } else {
-	//        return fmt.Errorf("no entry in arp")
-	//    }
-
-	return nil
+	return sendPkt(etherFrame, intf)
 }
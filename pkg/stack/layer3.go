@@ -1,13 +1,20 @@
 package stack
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
-	"github.com/gkarthikreddi/tcp/tools"
 )
 
+// routeLock guards the routing tables, the listeners look routes up while the cli and the
+// link state changes edit them
+var routeLock sync.Mutex
+
 func AddRoutingTableEntry(node *network.Node, routEntry *network.RoutEntry) {
+	routeLock.Lock()
+	defer routeLock.Unlock()
 	routingTable := network.GetNodeRoutingTable(node)
 	if routingTable == nil {
 		network.AssignNodeRoutingTable(node, routEntry)
@@ -19,11 +26,77 @@ func AddRoutingTableEntry(node *network.Node, routEntry *network.RoutEntry) {
 		} else {
 			routEntry.Next = routingTable.Next
 			routEntry.Prev = routingTable
+			if routingTable.Next != nil {
+				routingTable.Next.Prev = routEntry
+			}
 			routingTable.Next = routEntry
 		}
 	}
 }
 
+func deleteRoutingTableEntry(node *network.Node, routEntry *network.RoutEntry) {
+	for entry := network.GetNodeRoutingTable(node); entry != nil; entry = entry.Next {
+		if entry == routEntry {
+			if entry.Prev != nil && entry.Next != nil {
+				entry.Prev.Next = entry.Next
+				entry.Next.Prev = entry.Prev
+			} else if entry.Prev == nil && entry.Next == nil {
+				network.AssignNodeRoutingTable(node, nil)
+			} else if entry.Prev == nil {
+				entry.Next.Prev = nil
+				network.AssignNodeRoutingTable(node, entry.Next)
+			} else {
+				entry.Prev.Next = nil
+			}
+			break
+		}
+	}
+}
+
+// addIntfDirectRoute installs the directly connected route of an L3 interface that is up
+func addIntfDirectRoute(node *network.Node, intf *network.Interface) {
+	if network.IsIntfIp(intf) && network.IsIntfUp(intf) {
+		newEntry := network.RoutEntry{DstIpAddr: intfSubnet(intf), IsDirect: true, GatewayIp: nil, OutIntf: "NA"}
+		AddRoutingTableEntry(node, &newEntry)
+	}
+}
+
+// intfSubnet is the network the interface is attached to, destination of its direct route
+func intfSubnet(intf *network.Interface) *network.Ip {
+	ip := network.GetIntfIp(intf)
+	return &network.Ip{Addr: network.ApplyMask(ip), Mask: ip.Mask}
+}
+
+// flushIntfRoutes removes the directly connected route of the interface and every route going out of it
+func flushIntfRoutes(node *network.Node, intf *network.Interface) {
+	deleteIntfDirectRoute(node, intf)
+
+	routeLock.Lock()
+	defer routeLock.Unlock()
+	for entry := network.GetNodeRoutingTable(node); entry != nil; entry = entry.Next {
+		if entry.OutIntf == intf.Name {
+			deleteRoutingTableEntry(node, entry)
+		}
+	}
+}
+
+func deleteIntfDirectRoute(node *network.Node, intf *network.Interface) {
+	subnet := intfSubnet(intf)
+	routeLock.Lock()
+	defer routeLock.Unlock()
+	for entry := network.GetNodeRoutingTable(node); entry != nil; entry = entry.Next {
+		if entry.IsDirect && network.IsIntfIp(intf) && *entry.DstIpAddr == *subnet {
+			deleteRoutingTableEntry(node, entry)
+		}
+	}
+}
+
+// flushIntfNeighbors forgets the arp and mac entries learned on the interface
+func flushIntfNeighbors(node *network.Node, name string) {
+	flushArpTableIntf(node, name, false)
+	flushMacTableIntf(node, name)
+}
+
 func findDuplicateEntry(routingTable *network.RoutEntry, dstIp *network.Ip) *network.RoutEntry {
 	for entry := routingTable; entry != nil; entry = entry.Next {
 		if entry.DstIpAddr.Addr == dstIp.Addr {
@@ -33,6 +106,33 @@ func findDuplicateEntry(routingTable *network.RoutEntry, dstIp *network.Ip) *net
 	return nil
 }
 
+// nodeRouteLookup returns a copy of the route of the node to dst, nil when there is none
+func nodeRouteLookup(node *network.Node, dst [4]byte) *network.RoutEntry {
+	routeLock.Lock()
+	defer routeLock.Unlock()
+	route := routingTableLookup(network.GetNodeRoutingTable(node), &network.Ip{Addr: dst})
+	if route == nil {
+		return nil
+	}
+	dup := *route
+	dup.Next, dup.Prev = nil, nil
+	return &dup
+}
+
+// GetRoutingTable returns a copy of the routing table of the node
+func GetRoutingTable(node *network.Node) []network.RoutEntry {
+	routeLock.Lock()
+	defer routeLock.Unlock()
+
+	var routes []network.RoutEntry
+	for entry := network.GetNodeRoutingTable(node); entry != nil; entry = entry.Next {
+		dup := *entry
+		dup.Next, dup.Prev = nil, nil
+		routes = append(routes, dup)
+	}
+	return routes
+}
+
 func routingTableLookup(routingTable *network.RoutEntry, dstIp *network.Ip) *network.RoutEntry {
 	var ans *network.RoutEntry
 	var lpm uint8 // Longest Prefix Match
@@ -52,16 +152,15 @@ func InitRoutingTable(graph *network.Graph) {
 	for node := graph.List; node != nil; node = node.Next {
 		if network.IsNodeIp(node) {
 			routEntry := network.RoutEntry{DstIpAddr: network.GetNodeIp(node), IsDirect: true, GatewayIp: nil, OutIntf: "NA"}
+			routeLock.Lock()
 			network.AssignNodeRoutingTable(node, &routEntry)
+			routeLock.Unlock()
 		}
 		for _, intf := range node.Intf {
 			if intf == nil {
 				break
 			}
-			if network.IsIntfIp(intf) {
-				newEntry := network.RoutEntry{DstIpAddr: network.GetIntfIp(intf), IsDirect: true, GatewayIp: nil, OutIntf: "NA"}
-				AddRoutingTableEntry(node, &newEntry)
-			}
+			addIntfDirectRoute(node, intf)
 		}
 	}
 }
@@ -69,7 +168,7 @@ func InitRoutingTable(graph *network.Graph) {
 func promotePktToLayer3(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) error {
 	switch etherFrame.EtherType {
 	case ETH_IP:
-		if ipFrame, err := tools.ByteToStruct(etherFrame.Payload[:], ipHeader{}); err == nil {
+		if ipFrame, err := decodeIp(etherFrame.Payload); err == nil {
 			l3recieveFrame(node, intf, ipFrame)
 		} else {
 			return fmt.Errorf("Error while extracting IP payload from etherFrame")
@@ -79,53 +178,88 @@ func promotePktToLayer3(node *network.Node, intf *network.Interface, etherFrame
 	return nil
 }
 
-func demotePktToLayer3(node *network.Node, dstIp *network.Ip, protocol uint8) error {
+// demotePktToLayer3 wraps the payload of an upper layer protocol in an IP packet and routes it.
+// Without a source address the one of the outgoing interface is used, so that replies find their way back.
+func demotePktToLayer3(node *network.Node, srcIp *[4]byte, dstIp *network.Ip, protocol uint8, payload []byte) error {
 	ipFrame := newIpHeader()
 	ipFrame.Protocol = protocol
-	ipFrame.SrcIpAddr = network.GetNodeIp(node).Addr
 	ipFrame.DstIpAddr = dstIp.Addr
+	ipFrame.Payload = payload
 
-	routingTable := network.GetNodeRoutingTable(node)
+	return sendIpPkt(node, srcIp, &ipFrame)
+}
+
+// sendIpPkt routes an IP packet originated by the node, the header fields are left as the caller set them
+func sendIpPkt(node *network.Node, srcIp *[4]byte, ipFrame *ipHeader) error {
+	dstIp := &network.Ip{Addr: ipFrame.DstIpAddr}
 	var nextHopIp *network.Ip
-	if route := routingTableLookup(routingTable, dstIp); route != nil {
+	if route := nodeRouteLookup(node, dstIp.Addr); route != nil {
 		if isDirectRoute(route) {
 			nextHopIp = dstIp
 		} else {
 			nextHopIp = route.GatewayIp
 		}
-        return demotePktToLayer2(node, nextHopIp, route.OutIntf, &ipFrame, ETH_IP)
+
+		if srcIp != nil {
+			ipFrame.SrcIpAddr = *srcIp
+		} else {
+			ipFrame.SrcIpAddr = selectSrcIp(node, route, dstIp)
+		}
+		return demotePktToLayer2(node, nextHopIp, route.OutIntf, ipFrame, ETH_IP)
 	} else {
-        return fmt.Errorf("Coudn't transfer packet received from application layer")
-    }
+		return errNetUnreachable
+	}
+}
 
+func selectSrcIp(node *network.Node, route *network.RoutEntry, dstIp *network.Ip) [4]byte {
+	if route.OutIntf != "NA" {
+		if intf, err := network.GetIntfByIntfName(node, route.OutIntf); err == nil && network.IsIntfIp(intf) {
+			return network.GetIntfIp(intf).Addr
+		}
+	} else if isLocalDelivery(node, dstIp) {
+		return dstIp.Addr
+	} else if intf, err := network.NodeGetMatchingSubnet(node, &network.Ip{Addr: dstIp.Addr}); err == nil {
+		return network.GetIntfIp(intf).Addr
+	}
+	return network.GetNodeIp(node).Addr
 }
 
 func l3recieveFrame(node *network.Node, intf *network.Interface, ipFrame *ipHeader) error {
-	routingTable := network.GetNodeRoutingTable(node)
 	ip := &network.Ip{Addr: ipFrame.DstIpAddr}
-	if route := routingTableLookup(routingTable, ip); route != nil {
-		if isDirectRoute(route) {
-			if isLocalDelivery(node, ip) {
-				switch ipFrame.Protocol {
-				case ICMP_PRO:
-					fmt.Println("Ip Addr: " + Yellow + tools.ConvertAddrToStr(ipFrame.DstIpAddr[:]) + Reset + " ping " + Green + "successful" + Reset)
-					break
-				}
-			} else {
-				demotePktToLayer2(node, nil, "", ipFrame, ETH_IP)
+	if isLocalDelivery(node, ip) {
+		if isIpFragment(ipFrame) {
+			if ipFrame = reassembleIp(node, ipFrame); ipFrame == nil {
+				return nil
 			}
-		} else {
-			ipFrame.TTL -= 1
-			if ipFrame.TTL == 0 {
-				return fmt.Errorf("Max TTL reached")
-			}
-
-			demotePktToLayer2(node, route.GatewayIp, route.OutIntf, ipFrame, ETH_IP)
 		}
-	} else {
-		return fmt.Errorf("Cound't forward packet has there is no route in the routing table")
+		switch ipFrame.Protocol {
+		case ICMP_PRO:
+			return processIcmp(node, ipFrame)
+		}
+		return sendIcmpError(node, ipFrame, ICMP_DEST_UNREACH, ICMP_PROTO_UNREACH)
 	}
-	return nil
+
+	route := nodeRouteLookup(node, ip.Addr)
+	if route == nil {
+		return sendIcmpError(node, ipFrame, ICMP_DEST_UNREACH, ICMP_NET_UNREACH)
+	}
+	if ipFrame.TTL <= 1 {
+		return sendIcmpError(node, ipFrame, ICMP_TIME_EXCEEDED, ICMP_TTL_EXCEEDED)
+	}
+	ipFrame.TTL -= 1
+
+	nextHopIp, outIntf := route.GatewayIp, route.OutIntf
+	if isDirectRoute(route) {
+		nextHopIp, outIntf = &network.Ip{Addr: ipFrame.DstIpAddr}, "NA"
+	}
+	err := demotePktToLayer2(node, nextHopIp, outIntf, ipFrame, ETH_IP)
+	if errors.Is(err, errFragNeeded) {
+		return sendIcmpFragNeeded(node, ipFrame, ipEgressMtu(node, nextHopIp, outIntf))
+	}
+	if code, ok := unreachableCode(err); ok {
+		return sendIcmpError(node, ipFrame, ICMP_DEST_UNREACH, code)
+	}
+	return err
 }
 
 func isDirectRoute(route *network.RoutEntry) bool {
@@ -137,7 +271,10 @@ func isLocalDelivery(node *network.Node, dstIp *network.Ip) bool {
 		return true
 	}
 	for _, intf := range node.Intf {
-		if network.GetIntfIp(intf).Addr == dstIp.Addr {
+		if intf == nil {
+			break
+		}
+		if network.IsIntfIp(intf) && network.GetIntfIp(intf).Addr == dstIp.Addr {
 			return true
 		}
 	}
@@ -1,15 +1,182 @@
 package stack
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools"
 )
 
-// for now will only implement ping functionality
-func Ping(node *network.Node, dstIPAddr [4]byte) {
-	ip := &network.Ip{Addr: dstIPAddr}
-    if err := demotePktToLayer3(node, ip, ICMP_PRO); err != nil {
-        fmt.Println(err)
-    }
+const (
+	PING_DEF_COUNT   = 5
+	PING_DEF_SIZE    = 56
+	PING_MAX_SIZE    = 65507 // largest echo payload of an IP datagram
+	PING_DEF_TIMEOUT = time.Second * 2
+
+	TRACE_DEF_HOPS    = 30
+	TRACE_PROBES      = 3 // probes sent for every hop
+	TRACE_DEF_TIMEOUT = time.Second
+)
+
+// Ping sends count echo requests of size payload bytes one after the other, each one waits
+// for its reply at most timeout, and prints the round trip statistics once done. With df the
+// requests larger than the mtu of a link on the way are dropped rather than fragmented.
+func Ping(node *network.Node, dstIPAddr [4]byte, count int, size int, timeout time.Duration, df bool) error {
+	id, replies := registerIcmpWaiter()
+	defer unregisterIcmpWaiter(id)
+
+	dst := tools.ConvertAddrToStr(dstIPAddr[:])
+	fmt.Printf("PING %s: %d data bytes\n", dst, size)
+
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	var received int
+	var min, max, total time.Duration
+	for seq := 1; seq <= count; seq++ {
+		request := icmpHeader{Type: ICMP_ECHO_REQ,
+			Identifier: id,
+			Sequence:   uint16(seq),
+			Payload:    payload,
+		}
+		ipFrame := newIpHeader()
+		ipFrame.Protocol = ICMP_PRO
+		ipFrame.DfFlag = df
+		ipFrame.DstIpAddr = dstIPAddr
+		ipFrame.Payload = encodeIcmp(&request)
+
+		sent := time.Now()
+		if err := sendIpPkt(node, nil, &ipFrame); err != nil {
+			code, ok := unreachableCode(err)
+			if !ok {
+				return err
+			}
+			reason := err.Error()
+			if errors.Is(err, errFragNeeded) {
+				reason += fmt.Sprintf(" (mtu %d)", dstEgressMtu(node, dstIPAddr))
+			}
+			fmt.Printf("icmp_seq=%d %s %s\n", seq, reason, icmpErrorMark(ICMP_DEST_UNREACH, code))
+			continue
+		}
+
+		if event, ok := waitIcmpEvent(replies, uint16(seq), sent.Add(timeout)); !ok {
+			fmt.Printf("Request timeout for icmp_seq %d\n", seq)
+		} else if event.Type != ICMP_ECHO_REP {
+			reason := icmpErrorStr(event.Type, event.Code)
+			if event.Type == ICMP_DEST_UNREACH && event.Code == ICMP_FRAG_NEEDED {
+				reason += fmt.Sprintf(" (mtu %d)", event.Mtu)
+			}
+			fmt.Printf("From %s icmp_seq=%d %s %s\n", tools.ConvertAddrToStr(event.From[:]), seq,
+				reason, icmpErrorMark(event.Type, event.Code))
+		} else {
+			rtt := event.Time.Sub(sent)
+			fmt.Printf("%d bytes from %s: icmp_seq=%d ttl=%d time=%.3f ms\n",
+				event.Size, tools.ConvertAddrToStr(event.From[:]), seq, event.TTL, msec(rtt))
+
+			if received == 0 || rtt < min {
+				min = rtt
+			}
+			if rtt > max {
+				max = rtt
+			}
+			total += rtt
+			received++
+		}
+	}
+
+	fmt.Printf("--- %s ping statistics ---\n", dst)
+	fmt.Printf("%d packets transmitted, %d packets received, %.1f%% packet loss\n",
+		count, received, float64(count-received)*100/float64(count))
+	if received > 0 {
+		fmt.Printf("round-trip min/avg/max = %.3f/%.3f/%.3f ms\n",
+			msec(min), msec(total/time.Duration(received)), msec(max))
+	}
+	return nil
+}
+
+// Traceroute sends echo requests with an increasing TTL, every router on the way answers
+// with a time exceeded until the destination itself replies or maxHops is reached.
+func Traceroute(node *network.Node, dstIPAddr [4]byte, maxHops int, timeout time.Duration) error {
+	id, replies := registerIcmpWaiter()
+	defer unregisterIcmpWaiter(id)
+
+	fmt.Printf("traceroute to %s, %d hops max\n", tools.ConvertAddrToStr(dstIPAddr[:]), maxHops)
+
+	var seq uint16
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		fmt.Printf("%2d ", ttl)
+
+		var from [4]byte
+		done := false
+		for probe := 0; probe < TRACE_PROBES; probe++ {
+			seq++
+			request := icmpHeader{Type: ICMP_ECHO_REQ, Identifier: id, Sequence: seq}
+			ipFrame := newIpHeader()
+			ipFrame.Protocol = ICMP_PRO
+			ipFrame.TTL = uint8(ttl)
+			ipFrame.DstIpAddr = dstIPAddr
+			ipFrame.Payload = encodeIcmp(&request)
+
+			sent := time.Now()
+			if err := sendIpPkt(node, nil, &ipFrame); err != nil {
+				code, ok := unreachableCode(err)
+				if !ok {
+					fmt.Println()
+					return err
+				}
+				fmt.Printf(" %s", icmpErrorMark(ICMP_DEST_UNREACH, code))
+				done = true
+				continue
+			}
+
+			event, ok := waitIcmpEvent(replies, seq, sent.Add(timeout))
+			if !ok {
+				fmt.Print(" *")
+				continue
+			}
+			if event.From != from {
+				from = event.From
+				fmt.Printf(" %s", tools.ConvertAddrToStr(from[:]))
+			}
+			fmt.Printf("  %.3f ms", msec(event.Time.Sub(sent)))
+			if mark := icmpErrorMark(event.Type, event.Code); mark != "" {
+				fmt.Printf(" %s", mark)
+			}
+			if event.Type != ICMP_TIME_EXCEEDED {
+				done = true
+			}
+		}
+		fmt.Println()
+
+		if done {
+			break
+		}
+	}
+	return nil
+}
+
+// waitIcmpEvent returns what came back for the request with the given sequence number,
+// late answers to earlier requests are discarded
+func waitIcmpEvent(events chan icmpEvent, seq uint16, deadline time.Time) (icmpEvent, bool) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if event.Sequence == seq {
+				return event, true
+			}
+		case <-timer.C:
+			return icmpEvent{}, false
+		}
+	}
+}
+
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
 }
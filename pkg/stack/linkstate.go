@@ -0,0 +1,29 @@
+package stack
+
+import (
+	"fmt"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// InitLinkStateHandling keeps the tables of a node consistent with the state of its interfaces
+func InitLinkStateHandling() {
+	network.SubscribeLinkState(linkStateChanged)
+}
+
+func linkStateChanged(intf *network.Interface, up bool) {
+	node := intf.Att_node
+	if node == nil {
+		return
+	}
+
+	if up {
+		fmt.Println(Cyan + "Interface " + Yellow + node.Name + ":" + intf.Name + Cyan + " changed state to " + Green + "up" + Reset)
+		addIntfDirectRoute(node, intf)
+		go sendGratuitousArp(node, intf)
+	} else {
+		fmt.Println(Cyan + "Interface " + Yellow + node.Name + ":" + intf.Name + Cyan + " changed state to " + Purple + "down" + Reset)
+		deleteIntfDirectRoute(node, intf)
+		flushIntfNeighbors(node, intf.Name)
+	}
+}
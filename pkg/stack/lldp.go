@@ -0,0 +1,197 @@
+package stack
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Every wired interface advertises its node with LLDP (802.1AB), the neighbors heard are
+// kept until their TTL runs out. Lldpdus go to the nearest bridge addr, no switch forwards them.
+
+const (
+	LLDP_TX_INTERVAL     = time.Second * 5
+	LLDP_HOLD_MULTIPLIER = 4 // the TTL advertised is that many tx intervals
+	LLDP_TICK            = time.Millisecond * 500
+
+	ETH_LLDP = 0x88cc
+
+	LLDP_TLV_END       = 0
+	LLDP_TLV_CHASSIS   = 1
+	LLDP_TLV_PORT      = 2
+	LLDP_TLV_TTL       = 3
+	LLDP_TLV_SYS_NAME  = 5
+	LLDP_TLV_MGMT_ADDR = 8
+
+	LLDP_CHASSIS_MAC   = 4 // chassis id subtype, a mac addr
+	LLDP_PORT_NAME     = 5 // port id subtype, the interface name
+	LLDP_ADDR_IPV4     = 1 // management addr family
+	LLDP_IFNUM_IFINDEX = 2
+)
+
+var lldpMulticastMac = [6]byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+
+type lldpHeader struct {
+	ChassisId [6]byte
+	PortId    string
+	Ttl       uint16 // seconds, 0 withdraws the sender
+	SysName   string
+	MgmtAddr  *[4]byte // nil when the sender has no ip addr
+	IfIndex   uint32
+}
+
+// LldpNeighbor is what was heard last on a local interface
+type LldpNeighbor struct {
+	Intf      string
+	ChassisId [6]byte
+	PortId    string
+	SysName   string
+	MgmtAddr  *[4]byte
+	Ttl       uint16
+	Expires   time.Time
+}
+
+var (
+	lldpLock      sync.Mutex
+	lldpNeighbors = map[*network.Node]map[string]*LldpNeighbor{}
+	lldpLastTx    = map[*network.Interface]time.Time{}
+)
+
+func InitLldp(graph *network.Graph) {
+	go func() {
+		for range time.Tick(LLDP_TICK) {
+			for node := graph.List; node != nil; node = node.Next {
+				lldpTick(node)
+			}
+		}
+	}()
+}
+
+// lldpMgmtAddr is the loopback of the node, or the first ip addr of its interfaces
+func lldpMgmtAddr(node *network.Node) *[4]byte {
+	if addr := network.GetNodeIp(node).Addr; addr != [4]byte{} {
+		return &addr
+	}
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		if network.IsIntfIp(intf) {
+			addr := network.GetIntfIp(intf).Addr
+			return &addr
+		}
+	}
+	return nil
+}
+
+// lldpSendsOn tells whether the interface advertises the node, only the wired ones do
+// and a monitor destination carries nothing but the copies
+func lldpSendsOn(intf *network.Interface) bool {
+	return network.IsIntfLinked(intf) && network.IsIntfUp(intf) && !network.IsIntfMonitorDest(intf)
+}
+
+func lldpTick(node *network.Node) {
+	lldpLock.Lock()
+	// neighbors of interfaces gone or down are forgotten along with the expired ones
+	for name, nbr := range lldpNeighbors[node] {
+		intf, err := network.GetIntfByIntfName(node, name)
+		if err != nil || !network.IsIntfUp(intf) || time.Now().After(nbr.Expires) {
+			delete(lldpNeighbors[node], name)
+		}
+	}
+
+	var txs []*network.Interface
+	var frames []*ethernetHeader
+	for i, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		if !lldpSendsOn(intf) {
+			delete(lldpLastTx, intf)
+			continue
+		}
+		if time.Since(lldpLastTx[intf]) < LLDP_TX_INTERVAL {
+			continue
+		}
+		lldpLastTx[intf] = time.Now()
+
+		lldp := lldpHeader{ChassisId: network.GetNodeSystemMac(node).Addr,
+			PortId:   intf.Name,
+			Ttl:      uint16(LLDP_TX_INTERVAL * LLDP_HOLD_MULTIPLIER / time.Second),
+			SysName:  node.Name,
+			MgmtAddr: lldpMgmtAddr(node),
+			IfIndex:  uint32(i + 1)}
+		txs = append(txs, intf)
+		frames = append(frames, &ethernetHeader{DstMacAddr: lldpMulticastMac,
+			SrcMacAddr: network.GetIntfMac(intf).Addr,
+			EtherType:  ETH_LLDP,
+			Payload:    encodeLldp(&lldp)})
+	}
+	lldpLock.Unlock()
+
+	for i, intf := range txs {
+		sendPkt(frames[i], intf)
+	}
+}
+
+func isLldpdu(etherFrame *ethernetHeader) bool {
+	return etherFrame.DstMacAddr == lldpMulticastMac && etherFrame.EtherType == ETH_LLDP
+}
+
+// processLldpdu records the sender as the neighbor of the interface, a TTL of 0 withdraws it
+func processLldpdu(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) {
+	lldp, err := decodeLldp(etherFrame.Payload)
+	if err != nil {
+		return
+	}
+
+	lldpLock.Lock()
+	defer lldpLock.Unlock()
+	if lldp.Ttl == 0 {
+		delete(lldpNeighbors[node], intf.Name)
+		return
+	}
+	if lldpNeighbors[node] == nil {
+		lldpNeighbors[node] = map[string]*LldpNeighbor{}
+	}
+	lldpNeighbors[node][intf.Name] = &LldpNeighbor{Intf: intf.Name,
+		ChassisId: lldp.ChassisId,
+		PortId:    lldp.PortId,
+		SysName:   lldp.SysName,
+		MgmtAddr:  lldp.MgmtAddr,
+		Ttl:       lldp.Ttl,
+		Expires:   time.Now().Add(time.Duration(lldp.Ttl) * time.Second)}
+}
+
+// GetLldpNeighbors returns the neighbors of the node in the order of its interfaces
+func GetLldpNeighbors(node *network.Node) []LldpNeighbor {
+	lldpLock.Lock()
+	defer lldpLock.Unlock()
+
+	var nbrs []LldpNeighbor
+	for _, nbr := range lldpNeighbors[node] {
+		intf, err := network.GetIntfByIntfName(node, nbr.Intf)
+		if err == nil && network.IsIntfUp(intf) && time.Now().Before(nbr.Expires) {
+			nbrs = append(nbrs, *nbr)
+		}
+	}
+
+	index := map[string]int{}
+	for i, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		index[intf.Name] = i
+	}
+	sort.Slice(nbrs, func(i, j int) bool { return index[nbrs[i].Intf] < index[nbrs[j].Intf] })
+	return nbrs
+}
+
+// ClearLldpNeighbors forgets the neighbors of the node, they are heard again within a tx interval
+func ClearLldpNeighbors(node *network.Node) {
+	lldpLock.Lock()
+	defer lldpLock.Unlock()
+	delete(lldpNeighbors, node)
+}
@@ -0,0 +1,103 @@
+package stack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Learned mac entries are forgotten once no frame came from the mac addr for macTimeout,
+// so that a host moving to another port is found again by flooding.
+
+const (
+	MAC_DEF_TIMEOUT    = time.Second * 300
+	MAC_AGING_INTERVAL = time.Second
+)
+
+var macTimeout = MAC_DEF_TIMEOUT
+
+func SetMacTimeout(timeout time.Duration) {
+	macLock.Lock()
+	defer macLock.Unlock()
+	macTimeout = timeout
+}
+
+func InitMacAging(graph *network.Graph) {
+	go func() {
+		for range time.Tick(MAC_AGING_INTERVAL) {
+			for node := graph.List; node != nil; node = node.Next {
+				ageMacTable(node)
+			}
+		}
+	}()
+}
+
+func ageMacTable(node *network.Node) {
+	macLock.Lock()
+	defer macLock.Unlock()
+
+	for entry := network.GetNodeMacTable(node); entry != nil; entry = entry.Next {
+		if !entry.IsStatic && time.Since(entry.Updated) > macTimeout {
+			deleteMacTableEntry(node, entry.Vlan, entry.MacAddr.Addr)
+		}
+	}
+}
+
+func AddStaticMacEntry(node *network.Node, mac [6]byte, vlan uint16, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if network.IsIntfIp(intf) {
+		return fmt.Errorf("Interface: %s is not in L2 mode", name)
+	}
+
+	macLock.Lock()
+	defer macLock.Unlock()
+	addMacTableEntry(node, &network.MacEntry{Vlan: vlan,
+		MacAddr:  &network.Mac{Addr: mac},
+		Name:     name,
+		IsStatic: true,
+		Updated:  time.Now()})
+	return nil
+}
+
+func DeleteStaticMacEntry(node *network.Node, mac [6]byte, vlan uint16) error {
+	macLock.Lock()
+	defer macLock.Unlock()
+
+	entry := macTableLookup(network.GetNodeMacTable(node), vlan, mac)
+	if entry == nil || !entry.IsStatic {
+		return fmt.Errorf("No static mac entry for the given mac addr and vlan")
+	}
+	deleteMacTableEntry(node, vlan, mac)
+	return nil
+}
+
+// GetMacTable returns a copy of the mac table of the node
+func GetMacTable(node *network.Node) []network.MacEntry {
+	macLock.Lock()
+	defer macLock.Unlock()
+
+	var entries []network.MacEntry
+	for entry := network.GetNodeMacTable(node); entry != nil; entry = entry.Next {
+		dup := *entry
+		dup.MacAddr = &network.Mac{Addr: entry.MacAddr.Addr}
+		dup.Next, dup.Prev = nil, nil
+		entries = append(entries, dup)
+	}
+	return entries
+}
+
+// ClearMacTable removes the learned entries of the node, static entries are kept
+func ClearMacTable(node *network.Node) {
+	macLock.Lock()
+	defer macLock.Unlock()
+
+	for entry := network.GetNodeMacTable(node); entry != nil; entry = entry.Next {
+		if !entry.IsStatic {
+			deleteMacTableEntry(node, entry.Vlan, entry.MacAddr.Addr)
+		}
+	}
+}
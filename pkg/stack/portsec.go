@@ -0,0 +1,193 @@
+package stack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools"
+)
+
+// PortSecCounters is the state of a port with port security
+type PortSecCounters struct {
+	Current    int    // mac addrs learned on the port
+	Violations uint64 // frames refused in restrict or shutdown mode
+	LastMac    [6]byte
+	LastVlan   uint16
+	LastAt     time.Time // zero until the first violation
+}
+
+var (
+	portSecLock  sync.Mutex
+	portSecPorts = map[*network.Interface]*PortSecCounters{}
+)
+
+// InitPortSecurity installs the sticky mac addrs of the topology in the mac tables
+func InitPortSecurity(graph *network.Graph) {
+	for node := graph.List; node != nil; node = node.Next {
+		for _, intf := range node.Intf {
+			if intf == nil {
+				break
+			}
+			installStickyMacs(node, intf)
+		}
+	}
+}
+
+func installStickyMacs(node *network.Node, intf *network.Interface) {
+	sec := network.GetIntfPortSecurity(intf)
+	if sec == nil {
+		return
+	}
+
+	macLock.Lock()
+	defer macLock.Unlock()
+	for _, val := range sec.StickyMacs {
+		addMacTableEntry(node, &network.MacEntry{Vlan: val.Vlan,
+			MacAddr:  &network.Mac{Addr: val.Mac.Addr},
+			Name:     intf.Name,
+			IsStatic: true,
+			Updated:  time.Now()})
+	}
+}
+
+// removeStickyMacs forgets the sticky mac addrs of the port, they are about to leave its config
+func removeStickyMacs(node *network.Node, intf *network.Interface) {
+	sec := network.GetIntfPortSecurity(intf)
+	if sec == nil {
+		return
+	}
+
+	macLock.Lock()
+	defer macLock.Unlock()
+	for _, val := range sec.StickyMacs {
+		if entry := macTableLookup(network.GetNodeMacTable(node), val.Vlan, val.Mac.Addr); entry != nil && entry.Name == intf.Name {
+			deleteMacTableEntry(node, val.Vlan, val.Mac.Addr)
+		}
+	}
+}
+
+func countIntfMacs(node *network.Node, name string) int {
+	count := 0
+	for entry := network.GetNodeMacTable(node); entry != nil; entry = entry.Next {
+		if entry.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// learnSrcMac adds the source of a received frame to the mac table. A secure port refuses
+// a new addr once it learned its maximum, the frame is dropped then.
+func learnSrcMac(node *network.Node, intf *network.Interface, macEntry *network.MacEntry) bool {
+	sec := network.GetIntfPortSecurity(intf)
+	macLock.Lock()
+	if sec == nil || network.GetIntfL2Mode(intf) != network.ACCESS {
+		addMacTableEntry(node, macEntry)
+		macLock.Unlock()
+		return true
+	}
+
+	entry := macTableLookup(network.GetNodeMacTable(node), macEntry.Vlan, macEntry.MacAddr.Addr)
+	if entry != nil && entry.Name == intf.Name {
+		// already secured on this port
+		if !entry.IsStatic {
+			entry.Updated = macEntry.Updated
+		}
+		macLock.Unlock()
+		return true
+	}
+	// a static addr of another port can't move to this one
+	if (entry == nil || !entry.IsStatic) && countIntfMacs(node, intf.Name) < sec.Maximum {
+		if sec.Sticky && network.NodeAddIntfStickyMac(intf, macEntry.Vlan, macEntry.MacAddr.Addr) == nil {
+			macEntry.IsStatic = true
+		}
+		addMacTableEntry(node, macEntry)
+		macLock.Unlock()
+		return true
+	}
+	macLock.Unlock()
+
+	portSecViolation(node, intf, sec, macEntry)
+	return false
+}
+
+func portSecViolation(node *network.Node, intf *network.Interface, sec *network.PortSecurity, macEntry *network.MacEntry) {
+	if sec.Violation == network.PORTSEC_PROTECT {
+		return
+	}
+
+	portSecLock.Lock()
+	counters := portSecPorts[intf]
+	if counters == nil {
+		counters = &PortSecCounters{}
+		portSecPorts[intf] = counters
+	}
+	counters.Violations++
+	counters.LastMac, counters.LastVlan, counters.LastAt = macEntry.MacAddr.Addr, macEntry.Vlan, time.Now()
+	portSecLock.Unlock()
+
+	if sec.Violation == network.PORTSEC_SHUTDOWN {
+		if network.IsIntfErrDisabled(intf) {
+			return
+		}
+		fmt.Println(Cyan + "Interface " + Yellow + node.Name + ":" + intf.Name + Cyan + " port security violation by " + Yellow + tools.ConvertAddrToStr(macEntry.MacAddr.Addr[:]) + Cyan + ", " + Red + "err-disabling" + Cyan + " the port" + Reset)
+		network.NodeErrDisableIntf(node, intf.Name)
+		return
+	}
+	fmt.Println(Cyan + "Interface " + Yellow + node.Name + ":" + intf.Name + Cyan + " port security violation by " + Yellow + tools.ConvertAddrToStr(macEntry.MacAddr.Addr[:]) + Cyan + ", frame dropped" + Reset)
+}
+
+func GetPortSecCounters(node *network.Node, intf *network.Interface) PortSecCounters {
+	var counters PortSecCounters
+	portSecLock.Lock()
+	if val := portSecPorts[intf]; val != nil {
+		counters = *val
+	}
+	portSecLock.Unlock()
+
+	macLock.Lock()
+	counters.Current = countIntfMacs(node, intf.Name)
+	macLock.Unlock()
+	return counters
+}
+
+// ClearPortSecCounters resets the violation counters of every port of the node
+func ClearPortSecCounters(node *network.Node) {
+	portSecLock.Lock()
+	defer portSecLock.Unlock()
+
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		delete(portSecPorts, intf)
+	}
+}
+
+// SetIntfPortSecSticky turns sticky learning on or off, the addrs learned so far on the
+// port are forgotten so that they are learned again the new way
+func SetIntfPortSecSticky(node *network.Node, name string, sticky bool) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	if !sticky {
+		removeStickyMacs(node, intf)
+	}
+	if err := network.NodeSetIntfPortSecSticky(node, name, sticky); err != nil {
+		return err
+	}
+	flushMacTableIntf(node, name)
+	return nil
+}
+
+func UnsetIntfPortSecurity(node *network.Node, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+	removeStickyMacs(node, intf)
+	return network.NodeUnsetIntfPortSecurity(node, name)
+}
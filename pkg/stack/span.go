@@ -0,0 +1,44 @@
+package stack
+
+import (
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// mirrorVlan is the vlan of a frame on the port, untagged frames belong to the vlan of an
+// access port, the native vlan of a trunk or the vlan of a vlan interface
+func mirrorVlan(intf *network.Interface, etherFrame *ethernetHeader) uint16 {
+	if etherFrame.Tagged != nil {
+		return etherFrame.Tagged.Id
+	}
+	if network.IsIntfSvi(intf) {
+		return network.GetIntfSviVlan(intf)
+	}
+	switch network.GetIntfL2Mode(intf) {
+	case network.ACCESS:
+		return network.GetIntfVlanMembership(intf)[0]
+	case network.TRUNK:
+		return network.GetIntfNativeVlan(intf)
+	}
+	return 0
+}
+
+// mirrorFrame copies a frame received (MONITOR_RX) or sent (MONITOR_TX) on the port out of
+// the destination of every monitor session watching the port in that direction
+func mirrorFrame(intf *network.Interface, etherFrame *ethernetHeader, dir network.MonitorDir) {
+	node := intf.Att_node
+	if node == nil {
+		return
+	}
+	for _, session := range network.GetNodeMonitorSessions(node) {
+		if !network.IsMonitorSource(session, intf.Name, dir) {
+			continue
+		}
+		if session.Vlan != 0 && mirrorVlan(intf, etherFrame) != session.Vlan {
+			continue
+		}
+		if dst, err := network.GetIntfByIntfName(node, session.Destination); err == nil {
+			mirrored := *etherFrame
+			sendPkt(&mirrored, dst)
+		}
+	}
+}
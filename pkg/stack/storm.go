@@ -0,0 +1,131 @@
+package stack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+const STORM_INTERVAL = time.Second // frames are counted over fixed windows of that length
+
+// stormPort counts the flooded frames received on a port with storm control
+type stormPort struct {
+	start time.Time
+	count map[network.StormType]uint32
+	rate  map[network.StormType]uint32 // frames received during the last full window
+	drops map[network.StormType]uint64
+}
+
+var (
+	stormLock  sync.Mutex
+	stormPorts = map[*network.Interface]*stormPort{}
+)
+
+// StormCounters is a snapshot of the storm control counters of a port
+type StormCounters struct {
+	Rate  map[network.StormType]uint32
+	Drops map[network.StormType]uint64
+}
+
+// stormType tells which kind of flooded frame it is, false for a frame to a known unicast addr
+func stormType(node *network.Node, etherFrame *ethernetHeader) (network.StormType, bool) {
+	if isBroadcastAddr(etherFrame.DstMacAddr) {
+		return network.STORM_BROADCAST, true
+	}
+	if etherFrame.DstMacAddr[0]&0x01 != 0 {
+		return network.STORM_MULTICAST, true
+	}
+
+	macLock.Lock()
+	defer macLock.Unlock()
+	if macTableLookup(network.GetNodeMacTable(node), frameVlan(etherFrame), etherFrame.DstMacAddr) == nil {
+		return network.STORM_UNICAST, true
+	}
+	return "", false
+}
+
+// stormControlAdmit counts the frame against the level of the port, the frame is
+// dropped when the level is exceeded, or the port err-disabled if configured so
+func stormControlAdmit(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) bool {
+	storm := network.GetIntfStormControl(intf)
+	if storm == nil {
+		return true
+	}
+	kind, ok := stormType(node, etherFrame)
+	if !ok {
+		return true
+	}
+	level, ok := storm.Levels[kind]
+	if !ok {
+		return true
+	}
+
+	stormLock.Lock()
+	port := stormPorts[intf]
+	if port == nil {
+		port = &stormPort{start: time.Now(),
+			count: map[network.StormType]uint32{},
+			rate:  map[network.StormType]uint32{},
+			drops: map[network.StormType]uint64{}}
+		stormPorts[intf] = port
+	}
+	if elapsed := time.Since(port.start); elapsed >= STORM_INTERVAL {
+		port.rate = port.count
+		if elapsed >= STORM_INTERVAL*2 {
+			// nothing was received during the last window
+			port.rate = map[network.StormType]uint32{}
+		}
+		port.count = map[network.StormType]uint32{}
+		port.start = time.Now()
+	}
+	port.count[kind]++
+	if port.count[kind] <= level {
+		stormLock.Unlock()
+		return true
+	}
+	port.drops[kind]++
+	stormLock.Unlock()
+
+	if storm.Action == network.STORM_SHUTDOWN && !network.IsIntfErrDisabled(intf) {
+		fmt.Println(Cyan + "Interface " + Yellow + node.Name + ":" + intf.Name + Cyan + " " + string(kind) + " storm detected, " + Red + "err-disabling" + Cyan + " the port" + Reset)
+		network.NodeErrDisableIntf(node, intf.Name)
+	}
+	return false
+}
+
+func GetStormCounters(intf *network.Interface) StormCounters {
+	stormLock.Lock()
+	defer stormLock.Unlock()
+
+	counters := StormCounters{Rate: map[network.StormType]uint32{}, Drops: map[network.StormType]uint64{}}
+	if port := stormPorts[intf]; port != nil {
+		rate := port.rate
+		if elapsed := time.Since(port.start); elapsed >= STORM_INTERVAL*2 {
+			rate = nil
+		} else if elapsed >= STORM_INTERVAL {
+			rate = port.count
+		}
+		for kind, val := range rate {
+			counters.Rate[kind] = val
+		}
+		for kind, val := range port.drops {
+			counters.Drops[kind] = val
+		}
+	}
+	return counters
+}
+
+// ClearStormCounters resets the drop counters of every port of the node
+func ClearStormCounters(node *network.Node) {
+	stormLock.Lock()
+	defer stormLock.Unlock()
+
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		delete(stormPorts, intf)
+	}
+}
@@ -0,0 +1,497 @@
+package stack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+	"github.com/gkarthikreddi/tcp/tools"
+)
+
+// Every node switching frames runs the spanning tree protocol of 802.1D on its L2 ports,
+// the ports that would close a loop are kept blocking. The timers are much shorter than
+// the standard ones so that a topology converges in a couple of seconds.
+
+const (
+	STP_HELLO_TIME    = time.Second
+	STP_MAX_AGE       = STP_HELLO_TIME * 3 // info not refreshed for that long is discarded
+	STP_FWD_DELAY     = time.Second        // time spent listening and then learning
+	STP_TICK          = time.Millisecond * 100
+	STP_DEF_PRIORITY  = 32768
+	STP_PRIORITY_STEP = 4096
+	STP_MAX_PRIORITY  = 61440
+	STP_PORT_PRIORITY = 128
+
+	BPDU_CONFIG  = 0x00
+	BPDU_TCN     = 0x80
+	BPDU_FLAG_TC = 0x01
+)
+
+var stpMulticastMac = [6]byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x00}
+
+type bpduHeader struct {
+	ProtocolId uint16
+	Version    uint8
+	Type       uint8
+
+	// Only in configuration bpdus
+	Flags      uint8
+	RootId     uint64
+	RootCost   uint32
+	BridgeId   uint64
+	PortId     uint16
+	MessageAge uint16
+	MaxAge     uint16
+	HelloTime  uint16
+	FwdDelay   uint16
+}
+
+// stpTx is a bpdu waiting to be sent once stpLock is released
+type stpTx struct {
+	intf  *network.Interface
+	frame *ethernetHeader
+}
+
+// protects the bridges, they are updated by the receiving goroutines and the stp timer
+var stpLock sync.Mutex
+
+func InitSpanningTree(graph *network.Graph) {
+	// bridges exist before the first frame is switched, so that loops are blocked from the start
+	for node := graph.List; node != nil; node = node.Next {
+		stpTick(node)
+	}
+	go func() {
+		for range time.Tick(STP_TICK) {
+			for node := graph.List; node != nil; node = node.Next {
+				stpTick(node)
+			}
+		}
+	}()
+}
+
+func stpTick(node *network.Node) {
+	stpLock.Lock()
+	var txs []stpTx
+	if bridge, tc := syncStpPorts(node); bridge != nil && bridge.Enabled {
+		for _, port := range bridge.Ports {
+			if port.Heard != nil && time.Since(port.HeardAt) > STP_MAX_AGE {
+				port.Heard = nil
+			}
+		}
+
+		rootId, rootCost, rootPort := bridge.RootId, bridge.RootCost, bridge.RootPort
+		tc = updateStpRoles(bridge) || tc
+		tc = advanceStpStates(bridge) || tc
+		if tc {
+			txs = stpTopologyChange(node, bridge)
+		}
+		if time.Since(bridge.LastHello) >= STP_HELLO_TIME ||
+			rootId != bridge.RootId || rootCost != bridge.RootCost || rootPort != bridge.RootPort {
+			txs = append(txs, stpHellos(node, bridge)...)
+		}
+	}
+	stpLock.Unlock()
+
+	sendStpFrames(txs)
+}
+
+func newStpBridge(node *network.Node) *network.StpBridge {
+	bridge := &network.StpBridge{Enabled: true,
+		Priority: STP_DEF_PRIORITY,
+		Mac:      *network.GetNodeSystemMac(node),
+		Ports:    map[string]*network.StpPort{}}
+	bridge.RootId = BridgeId(bridge)
+	return bridge
+}
+
+// BridgeId is the bridge priority followed by the mac addr of the bridge
+func BridgeId(bridge *network.StpBridge) uint64 {
+	id := uint64(bridge.Priority) << 48
+	for i, val := range bridge.Mac.Addr {
+		id |= uint64(val) << (8 * (5 - i))
+	}
+	return id
+}
+
+func isBridgePort(intf *network.Interface) bool {
+	mode := network.GetIntfL2Mode(intf)
+	// the members of a port-channel are part of the single port of the bundle
+	return !network.IsIntfIp(intf) && network.GetIntfChannel(intf) == nil && !network.IsIntfMonitorDest(intf) &&
+		(mode == network.ACCESS || mode == network.TRUNK)
+}
+
+// isEdgePort tells whether the port faces a host rather than another bridge
+func isEdgePort(intf *network.Interface) bool {
+	nbr, err := network.GetNbrNode(intf)
+	if err != nil {
+		return false
+	}
+	peer, err := network.GetIntfByIntfName(nbr, network.GetNbrIntf(intf))
+	return err == nil && network.IsIntfIp(peer)
+}
+
+func stpPathCost(intf *network.Interface) uint32 {
+	if network.IsIntfChannel(intf) {
+		// a bundle costs like its cheapest member
+		var cost uint
+		for _, member := range network.GetChannelMembers(intf) {
+			if c := network.GetLinkCost(member); cost == 0 || c < cost {
+				cost = c
+			}
+		}
+		return uint32(max(cost, 1))
+	}
+	return uint32(max(network.GetLinkCost(intf), 1))
+}
+
+// syncStpPorts follows the L2 ports of the node as they get configured, linked and brought up or down,
+// it reports whether a forwarding port went down
+func syncStpPorts(node *network.Node) (*network.StpBridge, bool) {
+	bridge := network.GetNodeStp(node)
+	if bridge == nil {
+		for _, intf := range node.Intf {
+			if intf == nil {
+				break
+			}
+			if isBridgePort(intf) {
+				bridge = newStpBridge(node)
+				network.AssignNodeStp(node, bridge)
+				break
+			}
+		}
+	}
+	if bridge == nil || !bridge.Enabled {
+		return bridge, false
+	}
+
+	tc := false
+	seen := map[string]bool{}
+	for i, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		if !isBridgePort(intf) {
+			continue
+		}
+		seen[intf.Name] = true
+
+		port := bridge.Ports[intf.Name]
+		if port == nil {
+			port = &network.StpPort{Id: STP_PORT_PRIORITY<<8 | uint16(i+1),
+				Role:  network.STP_NO_ROLE,
+				State: network.STP_DISABLED,
+				Since: time.Now()}
+			bridge.Ports[intf.Name] = port
+		}
+		port.Cost = stpPathCost(intf)
+		port.IsEdge = isEdgePort(intf)
+
+		if !network.IsIntfUp(intf) {
+			port.Role, port.Heard = network.STP_NO_ROLE, nil
+			tc = setStpState(port, network.STP_DISABLED) || tc
+		} else if port.State == network.STP_DISABLED {
+			setStpState(port, network.STP_BLOCKING)
+		}
+	}
+	for name := range bridge.Ports {
+		if !seen[name] {
+			delete(bridge.Ports, name)
+		}
+	}
+	return bridge, tc
+}
+
+// betterStpVector compares priority vectors field by field, lower values win
+func betterStpVector(a, b network.StpVector) bool {
+	if a.RootId != b.RootId {
+		return a.RootId < b.RootId
+	}
+	if a.RootCost != b.RootCost {
+		return a.RootCost < b.RootCost
+	}
+	if a.BridgeId != b.BridgeId {
+		return a.BridgeId < b.BridgeId
+	}
+	return a.PortId < b.PortId
+}
+
+// updateStpRoles elects the root from the info heard on the ports and assigns the port roles,
+// it reports whether a port started or stopped forwarding
+func updateStpRoles(bridge *network.StpBridge) bool {
+	own := BridgeId(bridge)
+	best := network.StpVector{RootId: own, BridgeId: own}
+	rootPort := ""
+	for name, port := range bridge.Ports {
+		if port.Heard == nil || port.State == network.STP_DISABLED {
+			continue
+		}
+		candidate := *port.Heard
+		candidate.RootCost += port.Cost
+		if betterStpVector(candidate, best) || candidate == best && rootPort != "" && port.Id < bridge.Ports[rootPort].Id {
+			best, rootPort = candidate, name
+		}
+	}
+	bridge.RootId, bridge.RootCost, bridge.RootPort = best.RootId, best.RootCost, rootPort
+
+	tc := false
+	for name, port := range bridge.Ports {
+		if port.State == network.STP_DISABLED {
+			continue
+		}
+
+		role := network.STP_DESIGNATED
+		if name == rootPort {
+			role = network.STP_ROOT
+		} else if port.Heard != nil && betterStpVector(*port.Heard, network.StpVector{RootId: bridge.RootId,
+			RootCost: bridge.RootCost,
+			BridgeId: own,
+			PortId:   port.Id}) {
+			// the segment has a better designated bridge already
+			role = network.STP_ALTERNATE
+		}
+
+		port.Role = role
+		switch {
+		case role == network.STP_ALTERNATE:
+			tc = setStpState(port, network.STP_BLOCKING) || tc
+		case port.IsEdge:
+			tc = setStpState(port, network.STP_FORWARDING) || tc
+		case port.State == network.STP_BLOCKING:
+			tc = setStpState(port, network.STP_LISTENING) || tc
+		}
+	}
+	return tc
+}
+
+// advanceStpStates moves the ports on their way to forwarding once the forward delay expired
+func advanceStpStates(bridge *network.StpBridge) bool {
+	tc := false
+	for _, port := range bridge.Ports {
+		if time.Since(port.Since) < STP_FWD_DELAY {
+			continue
+		}
+		switch port.State {
+		case network.STP_LISTENING:
+			tc = setStpState(port, network.STP_LEARNING) || tc
+		case network.STP_LEARNING:
+			tc = setStpState(port, network.STP_FORWARDING) || tc
+		}
+	}
+	return tc
+}
+
+// setStpState reports a topology change when a port leading to another bridge starts or stops forwarding
+func setStpState(port *network.StpPort, state network.StpState) bool {
+	if port.State == state {
+		return false
+	}
+	prev := port.State
+	port.State, port.Since = state, time.Now()
+	return !port.IsEdge && (state == network.STP_FORWARDING || prev == network.STP_FORWARDING)
+}
+
+// stpTopologyChange flushes the learned macs, the root announces the change in its bpdus
+// while the other bridges notify it through their root port
+func stpTopologyChange(node *network.Node, bridge *network.StpBridge) []stpTx {
+	ClearMacTable(node)
+	if bridge.RootPort == "" {
+		bridge.TcUntil = time.Now().Add(STP_MAX_AGE + STP_FWD_DELAY)
+		return nil
+	}
+
+	intf, err := network.GetIntfByIntfName(node, bridge.RootPort)
+	if err != nil {
+		return nil
+	}
+	return []stpTx{{intf: intf, frame: bpduFrame(bridge, &bpduHeader{Type: BPDU_TCN})}}
+}
+
+// stpHellos are the configuration bpdus sent on every designated port
+func stpHellos(node *network.Node, bridge *network.StpBridge) []stpTx {
+	bridge.LastHello = time.Now()
+
+	var flags uint8
+	if time.Now().Before(bridge.TcUntil) {
+		flags = BPDU_FLAG_TC
+	}
+
+	var txs []stpTx
+	for name, port := range bridge.Ports {
+		if port.Role != network.STP_DESIGNATED {
+			continue
+		}
+		intf, err := network.GetIntfByIntfName(node, name)
+		if err != nil {
+			continue
+		}
+		txs = append(txs, stpTx{intf: intf, frame: bpduFrame(bridge, &bpduHeader{Type: BPDU_CONFIG,
+			Flags:     flags,
+			RootId:    bridge.RootId,
+			RootCost:  bridge.RootCost,
+			BridgeId:  BridgeId(bridge),
+			PortId:    port.Id,
+			MaxAge:    stpTime(STP_MAX_AGE),
+			HelloTime: stpTime(STP_HELLO_TIME),
+			FwdDelay:  stpTime(STP_FWD_DELAY)})})
+	}
+	return txs
+}
+
+// stpTime converts a duration to the 1/256 of a second used by bpdus
+func stpTime(d time.Duration) uint16 {
+	return uint16(d * 256 / time.Second)
+}
+
+// bpduFrame wraps a bpdu in an untagged 802.3 frame, its type field holds the length of the payload
+func bpduFrame(bridge *network.StpBridge, bpdu *bpduHeader) *ethernetHeader {
+	payload := encodeBpdu(bpdu)
+	return &ethernetHeader{DstMacAddr: stpMulticastMac,
+		SrcMacAddr: bridge.Mac.Addr,
+		EtherType:  uint16(len(payload)),
+		Payload:    payload}
+}
+
+func sendStpFrames(txs []stpTx) {
+	for _, tx := range txs {
+		sendPkt(tx.frame, tx.intf)
+	}
+}
+
+func isBpdu(etherFrame *ethernetHeader) bool {
+	return etherFrame.DstMacAddr == stpMulticastMac
+}
+
+// processBpdu handles a bpdu received on an L2 port, bpdus are never switched
+func processBpdu(node *network.Node, intf *network.Interface, etherFrame *ethernetHeader) {
+	bpdu, err := decodeBpdu(etherFrame.Payload)
+	if err != nil {
+		return
+	}
+
+	stpLock.Lock()
+	var txs []stpTx
+	if bridge := network.GetNodeStp(node); bridge != nil && bridge.Enabled {
+		if port := bridge.Ports[intf.Name]; port != nil && port.State != network.STP_DISABLED {
+			txs = stpReceiveBpdu(node, bridge, intf.Name, port, bpdu)
+		}
+	}
+	stpLock.Unlock()
+
+	sendStpFrames(txs)
+}
+
+func stpReceiveBpdu(node *network.Node, bridge *network.StpBridge, name string, port *network.StpPort, bpdu *bpduHeader) []stpTx {
+	if bpdu.Type == BPDU_TCN {
+		// relayed toward the root, which then announces the change to the whole tree
+		if port.Role == network.STP_DESIGNATED {
+			return stpTopologyChange(node, bridge)
+		}
+		return nil
+	}
+
+	port.Heard = &network.StpVector{RootId: bpdu.RootId,
+		RootCost: bpdu.RootCost,
+		BridgeId: bpdu.BridgeId,
+		PortId:   bpdu.PortId}
+	port.HeardAt = time.Now()
+
+	rootId, rootCost, rootPort := bridge.RootId, bridge.RootCost, bridge.RootPort
+	var txs []stpTx
+	if updateStpRoles(bridge) {
+		txs = stpTopologyChange(node, bridge)
+	}
+
+	if bpdu.Flags&BPDU_FLAG_TC != 0 && bridge.RootPort == name {
+		if time.Now().After(bridge.TcUntil) {
+			ClearMacTable(node)
+		}
+		bridge.TcUntil = time.Now().Add(STP_HELLO_TIME * 2)
+	}
+
+	// tell the others right away about a new root, and the sender if it holds worse info than ours
+	if rootId != bridge.RootId || rootCost != bridge.RootCost || rootPort != bridge.RootPort ||
+		port.Role == network.STP_DESIGNATED {
+		txs = append(txs, stpHellos(node, bridge)...)
+	}
+	return txs
+}
+
+// stpPortState is the state of an L2 port, the ports of a node not running the spanning tree always forward
+func stpPortState(intf *network.Interface) network.StpState {
+	stpLock.Lock()
+	defer stpLock.Unlock()
+
+	bridge := network.GetNodeStp(intf.Att_node)
+	if bridge == nil || !bridge.Enabled {
+		return network.STP_FORWARDING
+	}
+	if port := bridge.Ports[intf.Name]; port != nil {
+		return port.State
+	}
+	// not picked up by the spanning tree yet
+	return network.STP_BLOCKING
+}
+
+func SetStpEnabled(node *network.Node, enable bool) {
+	stpLock.Lock()
+	bridge := network.GetNodeStp(node)
+	if bridge == nil {
+		bridge = newStpBridge(node)
+		network.AssignNodeStp(node, bridge)
+	} else if bridge.Enabled == enable {
+		stpLock.Unlock()
+		return
+	}
+
+	// ports start over from blocking when the spanning tree is turned back on
+	bridge.Enabled = enable
+	bridge.Ports = map[string]*network.StpPort{}
+	bridge.RootId, bridge.RootCost, bridge.RootPort = BridgeId(bridge), 0, ""
+	stpLock.Unlock()
+
+	ClearMacTable(node)
+}
+
+func SetStpPriority(node *network.Node, priority uint16) error {
+	if priority%STP_PRIORITY_STEP != 0 || priority > STP_MAX_PRIORITY {
+		return fmt.Errorf("Bridge priority must be a multiple of %d up to %d", STP_PRIORITY_STEP, STP_MAX_PRIORITY)
+	}
+
+	stpLock.Lock()
+	defer stpLock.Unlock()
+	bridge := network.GetNodeStp(node)
+	if bridge == nil {
+		bridge = newStpBridge(node)
+		network.AssignNodeStp(node, bridge)
+	}
+	bridge.Priority = priority
+	return nil
+}
+
+// GetStpBridge returns a copy of the spanning tree state of the node, nil if it doesn't switch frames
+func GetStpBridge(node *network.Node) *network.StpBridge {
+	stpLock.Lock()
+	defer stpLock.Unlock()
+
+	bridge := network.GetNodeStp(node)
+	if bridge == nil {
+		return nil
+	}
+	copied := *bridge
+	copied.Ports = map[string]*network.StpPort{}
+	for name, port := range bridge.Ports {
+		p := *port
+		copied.Ports[name] = &p
+	}
+	return &copied
+}
+
+// StpBridgeId formats the id the usual way, priority then mac addr
+func StpBridgeId(id uint64) string {
+	var mac [6]byte
+	for i := range mac {
+		mac[i] = byte(id >> (8 * (5 - i)))
+	}
+	return fmt.Sprintf("%d.%s", id>>48, tools.ConvertAddrToStr(mac[:]))
+}
@@ -0,0 +1,52 @@
+package stack
+
+import (
+	"fmt"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// nodeSvi returns the vlan interface of the node for the vlan, if it has one
+func nodeSvi(node *network.Node, vlan uint16) *network.Interface {
+	if vlan == 0 {
+		return nil
+	}
+	for _, intf := range node.Intf {
+		if intf == nil {
+			break
+		}
+		if network.GetIntfSviVlan(intf) == vlan {
+			return intf
+		}
+	}
+	return nil
+}
+
+// sviSendPkt hands a frame routed out of a vlan interface to the switch, tagged with the vlan
+func sviSendPkt(etherFrame *ethernetHeader, svi *network.Interface) error {
+	if !network.IsIntfUp(svi) {
+		return fmt.Errorf("Interface: %s is down", svi.Att_node.Name+":"+svi.Name)
+	}
+
+	taggedFrame := *etherFrame
+	taggedFrame.Tagged = &vlan8021qHeader{TPID: VLAN_TPID, Id: network.GetIntfSviVlan(svi)}
+	l2switchForwardFrame(svi.Att_node, svi, &taggedFrame)
+	return nil
+}
+
+// sviReceiveFrame promotes the frames switched to the vlan interface, broadcasts are also
+// flooded to the ports. It reports whether the frame was consumed.
+func sviReceiveFrame(node *network.Node, fromIntf *network.Interface, etherFrame *ethernetHeader) bool {
+	svi := nodeSvi(node, frameVlan(etherFrame))
+	if svi == nil || svi == fromIntf || !network.IsIntfUp(svi) || !network.IsIntfIp(svi) {
+		return false
+	}
+
+	forUs := etherFrame.DstMacAddr == network.GetIntfMac(svi).Addr
+	if forUs || isBroadcastAddr(etherFrame.DstMacAddr) {
+		untaggedFrame := *etherFrame
+		untaggedFrame.Tagged = nil
+		promotePktToLayer2(node, svi, &untaggedFrame)
+	}
+	return forUs
+}
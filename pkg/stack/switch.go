@@ -1,35 +1,53 @@
 package stack
 
-import "github.com/gkarthikreddi/tcp/pkg/network"
+import (
+	"sync"
+	"time"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
 
 type vlan8021qHeader struct {
 	TPID uint16
-	Id   uint16 // This should be 12 bits has there are PRI (3bits) and CFI (1bits)
+	PCP  uint8  // priority code point, 3 bits
+	DEI  bool   // drop eligible indicator
+	Id   uint16 // 12 bits
 }
 
+// protects the mac tables, they are updated by the receiving goroutines and the aging sweeper
+var macLock sync.Mutex
+
 func addMacTableEntry(node *network.Node, macEntry *network.MacEntry) {
 	macTable := network.GetNodeMacTable(node)
 	if macTable == nil {
 		network.AssignNodeMacTable(node, macEntry)
 		return
 	}
-	if oldEntry := macTableLookup(macTable, macEntry.MacAddr.Addr); oldEntry != nil {
-		if oldEntry.Name == macEntry.Name {
+	if oldEntry := macTableLookup(macTable, macEntry.Vlan, macEntry.MacAddr.Addr); oldEntry != nil {
+		if oldEntry.IsStatic && !macEntry.IsStatic {
+			return
+		}
+		if oldEntry.Name == macEntry.Name && oldEntry.IsStatic == macEntry.IsStatic {
+			oldEntry.Updated = macEntry.Updated
 			return
 		} else {
-			deleteMacTableEntry(node, oldEntry.MacAddr.Addr)
+			// the host moved to another port
+			deleteMacTableEntry(node, oldEntry.Vlan, oldEntry.MacAddr.Addr)
 			addMacTableEntry(node, macEntry)
 			return
 		}
 	}
 	macEntry.Next = macTable.Next
 	macEntry.Prev = macTable
+	if macTable.Next != nil {
+		macTable.Next.Prev = macEntry
+	}
 	macTable.Next = macEntry
 }
 
-func macTableLookup(macTable *network.MacEntry, macAddr [6]byte) *network.MacEntry {
+func macTableLookup(macTable *network.MacEntry, vlan uint16, macAddr [6]byte) *network.MacEntry {
 	for entry := macTable; entry != nil; entry = entry.Next {
-		if entry.MacAddr.Addr == macAddr {
+		if entry.Vlan == vlan && entry.MacAddr.Addr == macAddr {
 			return entry
 		}
 	}
@@ -37,10 +55,10 @@ func macTableLookup(macTable *network.MacEntry, macAddr [6]byte) *network.MacEnt
 	return nil
 }
 
-func deleteMacTableEntry(node *network.Node, macAddr [6]byte) {
+func deleteMacTableEntry(node *network.Node, vlan uint16, macAddr [6]byte) {
 	macTable := network.GetNodeMacTable(node)
 	for entry := macTable; entry != nil; entry = entry.Next {
-		if entry.MacAddr.Addr == macAddr {
+		if entry.Vlan == vlan && entry.MacAddr.Addr == macAddr {
 			if entry.Prev != nil && entry.Next != nil {
 				entry.Prev.Next = entry.Next
 				entry.Next.Prev = entry.Prev
@@ -52,33 +70,78 @@ func deleteMacTableEntry(node *network.Node, macAddr [6]byte) {
 			} else {
 				entry.Prev.Next = nil
 			}
+			break
+		}
+	}
+}
+
+func flushMacTableIntf(node *network.Node, name string) {
+	macLock.Lock()
+	defer macLock.Unlock()
+
+	for entry := network.GetNodeMacTable(node); entry != nil; entry = entry.Next {
+		if entry.Name == name && !entry.IsStatic {
+			deleteMacTableEntry(node, entry.Vlan, entry.MacAddr.Addr)
 		}
-		break
 	}
 }
 
+// frameVlan is the vlan the frame is switched in, frames received on an access port got tagged already
+func frameVlan(etherFrame *ethernetHeader) uint16 {
+	if etherFrame.Tagged == nil {
+		return 0
+	}
+	return etherFrame.Tagged.Id
+}
+
 func l2switchReceiveFrame(localIntf *network.Interface, etherFrame *ethernetHeader) {
 	node := localIntf.Att_node
 	srcMac := etherFrame.SrcMacAddr
 
+	// blocking and listening ports neither learn nor forward
+	state := stpPortState(localIntf)
+	if state != network.STP_LEARNING && state != network.STP_FORWARDING {
+		return
+	}
+	if !stormControlAdmit(node, localIntf, etherFrame) {
+		return
+	}
+
 	// Perfrom Mac Learning
-	macEntry := network.MacEntry{MacAddr: &network.Mac{Addr: srcMac}, Name: localIntf.Name}
-	addMacTableEntry(node, &macEntry)
+	macEntry := network.MacEntry{Vlan: frameVlan(etherFrame),
+		MacAddr: &network.Mac{Addr: srcMac},
+		Name:    localIntf.Name,
+		Updated: time.Now()}
+	if !learnSrcMac(node, localIntf, &macEntry) {
+		return
+	}
+
+	if state != network.STP_FORWARDING {
+		return
+	}
 
 	// Forward frame
 	l2switchForwardFrame(node, localIntf, etherFrame)
 }
 
 func l2switchForwardFrame(node *network.Node, localIntf *network.Interface, etherFrame *ethernetHeader) {
+	if sviReceiveFrame(node, localIntf, etherFrame) {
+		return
+	}
 	if isBroadcastAddr(etherFrame.DstMacAddr) {
 		l2sendPktFlood(node, localIntf, etherFrame)
 		return
 	}
-	if macEntry := macTableLookup(network.GetNodeMacTable(node), etherFrame.DstMacAddr); macEntry != nil {
-		if intf, _ := network.GetIntfByIntfName(node, macEntry.Name); intf != nil {
-			l2switchSendPkt(etherFrame, intf)
-			return
-		}
+	macLock.Lock()
+	var name string
+	if macEntry := macTableLookup(network.GetNodeMacTable(node), frameVlan(etherFrame), etherFrame.DstMacAddr); macEntry != nil {
+		name = macEntry.Name
+	}
+	macLock.Unlock()
+
+	if intf, _ := network.GetIntfByIntfName(node, name); intf != nil {
+		l2switchSendPkt(etherFrame, intf)
+		return
 	}
 	l2sendPktFlood(node, localIntf, etherFrame)
 }
@@ -93,7 +156,9 @@ func l2sendPktFlood(node *network.Node, excludeIntf *network.Interface, etherFra
 }
 
 func l2switchSendPkt(etherFrame *ethernetHeader, outintf *network.Interface) {
-	if network.IsIntfIp(outintf) {
+	// members of a port-channel only carry what is sent on the port-channel
+	if network.IsIntfIp(outintf) || network.GetIntfChannel(outintf) != nil || network.IsIntfMonitorDest(outintf) ||
+		stpPortState(outintf) != network.STP_FORWARDING {
 		return
 	}
 
@@ -112,14 +177,14 @@ func l2switchSendPkt(etherFrame *ethernetHeader, outintf *network.Interface) {
 				sendPkt(&untaggedFrame, outintf)
 			}
 		}
-	} else if mode == network.TRUNK && etherFrame.Tagged != nil {
-		for _, val := range network.GetIntfVlanMembership(outintf) {
-			if val == 0 {
-				break
-			}
-			if val == etherFrame.Tagged.Id {
-				sendPkt(etherFrame, outintf)
-			}
+	} else if mode == network.TRUNK && etherFrame.Tagged != nil && network.IsIntfVlanMember(outintf, etherFrame.Tagged.Id) {
+		if etherFrame.Tagged.Id == network.GetIntfNativeVlan(outintf) {
+			// the native vlan crosses the trunk untagged
+			untaggedFrame := *etherFrame
+			untaggedFrame.Tagged = nil
+			sendPkt(&untaggedFrame, outintf)
+		} else {
+			sendPkt(etherFrame, outintf)
 		}
 	}
 }
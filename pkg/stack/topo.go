@@ -0,0 +1,129 @@
+package stack
+
+import (
+	"fmt"
+
+	"github.com/gkarthikreddi/tcp/pkg/network"
+)
+
+// Runtime topology editing, these wrap the graph operations of the network package
+// and keep the udp listeners and the arp/mac/routing tables in sync with them.
+
+func AddNode(graph *network.Graph, name string, lbAddr string) (*network.Node, error) {
+	if _, err := network.GetNodeByNodeName(graph, name); err == nil {
+		return nil, fmt.Errorf("Node: %s already exists", name)
+	}
+
+	node := network.CreateGraphNode(graph, name)
+	if network.NodeSetLbAddr(node, lbAddr) {
+		routEntry := network.RoutEntry{DstIpAddr: network.GetNodeIp(node), IsDirect: true, GatewayIp: nil, OutIntf: "NA"}
+		AddRoutingTableEntry(node, &routEntry)
+	}
+
+	if err := StartNodeListening(node); err != nil {
+		network.RemoveGraphNode(graph, node)
+		return nil, err
+	}
+	return node, nil
+}
+
+func RemoveNode(graph *network.Graph, node *network.Node) error {
+	for node.Intf[0] != nil {
+		if err := RemoveInterface(node, node.Intf[0].Name); err != nil {
+			return err
+		}
+	}
+
+	ClearLldpNeighbors(node)
+	StopNodeListening(node)
+	return network.RemoveGraphNode(graph, node)
+}
+
+func AddLink(node1, node2 *network.Node, intf1, intf2 string, cost uint) error {
+	return network.InsertLinkBetweenNodes(node1, node2, intf1, intf2, cost)
+}
+
+// RemoveLink unwires the interface, whatever both ends learned over the link is forgotten
+func RemoveLink(node *network.Node, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	if nbrNode, err := network.GetNbrNode(intf); err == nil {
+		flushIntfNeighbors(nbrNode, network.GetNbrIntf(intf))
+	}
+	flushIntfNeighbors(node, name)
+
+	return network.RemoveLink(node, name)
+}
+
+func RemoveInterface(node *network.Node, name string) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	if network.IsIntfLinked(intf) {
+		if err := RemoveLink(node, name); err != nil {
+			return err
+		}
+	}
+	flushIntfRoutes(node, intf)
+	// static arp entries go too, they would resolve onto an interface that no longer exists
+	flushArpTableIntf(node, name, true)
+	flushMacTableIntf(node, name)
+
+	return network.RemoveInterface(node, name)
+}
+
+func SetIntfIpAddr(node *network.Node, name, addr string, mask uint8) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		// configuring interface vlan<id> creates it
+		vlan, ok := network.ParseSviName(name)
+		if !ok {
+			return err
+		}
+		if intf, err = network.NodeAddSvi(node, vlan); err != nil {
+			return err
+		}
+	}
+
+	flushIntfRoutes(node, intf)
+	flushIntfNeighbors(node, name)
+	if !network.NodeSetIntfIpAddr(node, name, addr, mask) {
+		return fmt.Errorf("Can't assign ip addr to interface: %s", node.Name+":"+name)
+	}
+	addIntfDirectRoute(node, intf)
+
+	return sendGratuitousArp(node, intf)
+}
+
+func SetIntfMacAddr(node *network.Node, name string, mac [6]byte) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	if err := network.NodeSetIntfMacAddr(node, name, mac); err != nil {
+		return err
+	}
+
+	return sendGratuitousArp(node, intf)
+}
+
+func SetIntfL2Mode(node *network.Node, name string, mode network.L2Mode) error {
+	intf, err := network.GetIntfByIntfName(node, name)
+	if err != nil {
+		return err
+	}
+
+	flushIntfRoutes(node, intf)
+	flushIntfNeighbors(node, name)
+	if !network.NodeSetIntfL2Mode(node, name, mode) {
+		return fmt.Errorf("Can't set L2 mode on interface: %s", node.Name+":"+name)
+	}
+
+	return nil
+}
@@ -0,0 +1,425 @@
+package stack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// On the wire encoding of the headers, everything is in network byte order.
+
+const (
+	ETH_HDR_SIZE     = 14
+	ETH_MIN_PAYLOAD  = 46
+	ETH_FCS_SIZE     = 4
+	VLAN_TAG_SIZE    = 4
+	VLAN_TPID        = 0x8100
+	VLAN_DEI         = 0x1000
+	VLAN_VID_MASK    = 0x0fff
+	ARP_HDR_SIZE     = 28
+	IP_HDR_MIN_SIZE  = 20
+	MAX_INTF_NAME    = 255
+	MAX_PACKET_SIZE  = 65535
+	IP_FLAG_RESERVED = 0x8000
+	IP_FLAG_DF       = 0x4000
+	IP_FLAG_MF       = 0x2000
+	IP_FRAG_OFF_MASK = 0x1fff
+	LLC_HDR_SIZE     = 3
+	LLC_SAP_STP      = 0x42
+	LLC_UI           = 0x03
+	BPDU_CONFIG_SIZE = 35
+	BPDU_TCN_SIZE    = 4
+	LACPDU_SIZE      = 110
+	LACP_INFO_LEN    = 20
+	LACP_COLL_LEN    = 16
+)
+
+// encodeEthernet produces an Ethernet II frame, with an 802.1Q tag when the frame is tagged.
+// Short payloads are padded to the 46 bytes minimum and the FCS is appended.
+func encodeEthernet(frame *ethernetHeader) []byte {
+	size := ETH_HDR_SIZE + max(len(frame.Payload), ETH_MIN_PAYLOAD) + ETH_FCS_SIZE
+	if frame.Tagged != nil {
+		size += VLAN_TAG_SIZE
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, frame.DstMacAddr[:]...)
+	buf = append(buf, frame.SrcMacAddr[:]...)
+	if frame.Tagged != nil {
+		tci := uint16(frame.Tagged.PCP&0x7)<<13 | frame.Tagged.Id&VLAN_VID_MASK
+		if frame.Tagged.DEI {
+			tci |= VLAN_DEI
+		}
+		buf = binary.BigEndian.AppendUint16(buf, VLAN_TPID)
+		buf = binary.BigEndian.AppendUint16(buf, tci)
+	}
+	buf = binary.BigEndian.AppendUint16(buf, frame.EtherType)
+	buf = append(buf, frame.Payload...)
+	for len(buf) < size-ETH_FCS_SIZE {
+		buf = append(buf, 0)
+	}
+
+	frame.Fcs = crc32.ChecksumIEEE(buf)
+	return binary.LittleEndian.AppendUint32(buf, frame.Fcs)
+}
+
+func decodeEthernet(data []byte) (*ethernetHeader, error) {
+	if len(data) < ETH_HDR_SIZE+ETH_FCS_SIZE {
+		return nil, fmt.Errorf("Ethernet frame too short: %d bytes", len(data))
+	}
+
+	body := data[:len(data)-ETH_FCS_SIZE]
+	fcs := binary.LittleEndian.Uint32(data[len(data)-ETH_FCS_SIZE:])
+	if crc32.ChecksumIEEE(body) != fcs {
+		return nil, fmt.Errorf("Ethernet frame with bad FCS")
+	}
+
+	frame := &ethernetHeader{Fcs: fcs}
+	copy(frame.DstMacAddr[:], body[0:6])
+	copy(frame.SrcMacAddr[:], body[6:12])
+	body = body[12:]
+
+	if binary.BigEndian.Uint16(body) == VLAN_TPID {
+		if len(body) < VLAN_TAG_SIZE+2 {
+			return nil, fmt.Errorf("Truncated 802.1Q tag")
+		}
+		tci := binary.BigEndian.Uint16(body[2:])
+		frame.Tagged = &vlan8021qHeader{TPID: VLAN_TPID,
+			PCP: uint8(tci >> 13),
+			DEI: tci&VLAN_DEI != 0,
+			Id:  tci & VLAN_VID_MASK}
+		body = body[VLAN_TAG_SIZE:]
+	}
+	frame.EtherType = binary.BigEndian.Uint16(body)
+	frame.Payload = body[2:]
+
+	return frame, nil
+}
+
+// encodePacket wraps a frame into the udp datagram exchanged between nodes,
+// the frame is preceded by the name of the interface it is delivered to
+func encodePacket(pkt *packet) ([]byte, error) {
+	if len(pkt.Intf) > MAX_INTF_NAME {
+		return nil, fmt.Errorf("Interface name too long: %s", pkt.Intf)
+	}
+
+	buf := []byte{byte(len(pkt.Intf))}
+	buf = append(buf, pkt.Intf...)
+	return append(buf, encodeEthernet(&pkt.EtherFrame)...), nil
+}
+
+func decodePacket(data []byte) (*packet, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, fmt.Errorf("Truncated packet")
+	}
+
+	// the receive buffer is reused for the next datagram, frames must not point into it
+	data = append([]byte(nil), data...)
+
+	frame, err := decodeEthernet(data[1+int(data[0]):])
+	if err != nil {
+		return nil, err
+	}
+	return &packet{Intf: string(data[1 : 1+int(data[0])]), EtherFrame: *frame}, nil
+}
+
+// encodeArp follows RFC 826 for ethernet/IPv4
+func encodeArp(arp *arpHeader) []byte {
+	buf := make([]byte, 0, ARP_HDR_SIZE)
+	buf = binary.BigEndian.AppendUint16(buf, arp.HardwareType)
+	buf = binary.BigEndian.AppendUint16(buf, arp.ProtocolType)
+	buf = append(buf, arp.HardwareLength, arp.ProtocolLength)
+	buf = binary.BigEndian.AppendUint16(buf, arp.Operation)
+	buf = append(buf, arp.SrcMacAddr[:]...)
+	buf = append(buf, arp.SrcProtocolAddr[:]...)
+	buf = append(buf, arp.DstMacAddr[:]...)
+	return append(buf, arp.DstProtocolAddr[:]...)
+}
+
+func decodeArp(data []byte) (*arpHeader, error) {
+	if len(data) < ARP_HDR_SIZE {
+		return nil, fmt.Errorf("ARP message too short: %d bytes", len(data))
+	}
+
+	arp := &arpHeader{
+		HardwareType:   binary.BigEndian.Uint16(data[0:]),
+		ProtocolType:   binary.BigEndian.Uint16(data[2:]),
+		HardwareLength: data[4],
+		ProtocolLength: data[5],
+		Operation:      binary.BigEndian.Uint16(data[6:]),
+	}
+	if arp.HardwareLength != 6 || arp.ProtocolLength != 4 {
+		return nil, fmt.Errorf("Unsupported ARP address lengths")
+	}
+	copy(arp.SrcMacAddr[:], data[8:14])
+	copy(arp.SrcProtocolAddr[:], data[14:18])
+	copy(arp.DstMacAddr[:], data[18:24])
+	copy(arp.DstProtocolAddr[:], data[24:28])
+
+	return arp, nil
+}
+
+// encodeBpdu produces the LLC header and the bpdu carried by an 802.3 frame (802.1D 9.3),
+// times are in 1/256 of a second
+func encodeBpdu(bpdu *bpduHeader) []byte {
+	buf := make([]byte, 0, LLC_HDR_SIZE+BPDU_CONFIG_SIZE)
+	buf = append(buf, LLC_SAP_STP, LLC_SAP_STP, LLC_UI)
+	buf = binary.BigEndian.AppendUint16(buf, bpdu.ProtocolId)
+	buf = append(buf, bpdu.Version, bpdu.Type)
+	if bpdu.Type == BPDU_TCN {
+		return buf
+	}
+
+	buf = append(buf, bpdu.Flags)
+	buf = binary.BigEndian.AppendUint64(buf, bpdu.RootId)
+	buf = binary.BigEndian.AppendUint32(buf, bpdu.RootCost)
+	buf = binary.BigEndian.AppendUint64(buf, bpdu.BridgeId)
+	buf = binary.BigEndian.AppendUint16(buf, bpdu.PortId)
+	buf = binary.BigEndian.AppendUint16(buf, bpdu.MessageAge)
+	buf = binary.BigEndian.AppendUint16(buf, bpdu.MaxAge)
+	buf = binary.BigEndian.AppendUint16(buf, bpdu.HelloTime)
+	return binary.BigEndian.AppendUint16(buf, bpdu.FwdDelay)
+}
+
+func decodeBpdu(data []byte) (*bpduHeader, error) {
+	if len(data) < LLC_HDR_SIZE+BPDU_TCN_SIZE {
+		return nil, fmt.Errorf("BPDU too short: %d bytes", len(data))
+	}
+	if data[0] != LLC_SAP_STP || data[1] != LLC_SAP_STP || data[2] != LLC_UI {
+		return nil, fmt.Errorf("Frame is not a BPDU")
+	}
+	data = data[LLC_HDR_SIZE:]
+
+	bpdu := &bpduHeader{
+		ProtocolId: binary.BigEndian.Uint16(data[0:]),
+		Version:    data[2],
+		Type:       data[3],
+	}
+	switch bpdu.Type {
+	case BPDU_TCN:
+		return bpdu, nil
+	case BPDU_CONFIG:
+		if len(data) < BPDU_CONFIG_SIZE {
+			return nil, fmt.Errorf("BPDU too short: %d bytes", len(data))
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported BPDU type: %d", bpdu.Type)
+	}
+
+	bpdu.Flags = data[4]
+	bpdu.RootId = binary.BigEndian.Uint64(data[5:])
+	bpdu.RootCost = binary.BigEndian.Uint32(data[13:])
+	bpdu.BridgeId = binary.BigEndian.Uint64(data[17:])
+	bpdu.PortId = binary.BigEndian.Uint16(data[25:])
+	bpdu.MessageAge = binary.BigEndian.Uint16(data[27:])
+	bpdu.MaxAge = binary.BigEndian.Uint16(data[29:])
+	bpdu.HelloTime = binary.BigEndian.Uint16(data[31:])
+	bpdu.FwdDelay = binary.BigEndian.Uint16(data[33:])
+	return bpdu, nil
+}
+
+// encodeLacp follows 802.1AX for a LACPDU: actor, partner, collector and terminator tlvs
+func encodeLacp(lacp *lacpHeader) []byte {
+	buf := make([]byte, 0, LACPDU_SIZE)
+	buf = append(buf, LACP_SUBTYPE, LACP_VERSION)
+	for i, info := range []*lacpInfo{&lacp.Actor, &lacp.Partner} {
+		buf = append(buf, uint8(i+1), LACP_INFO_LEN)
+		buf = binary.BigEndian.AppendUint16(buf, info.SysPriority)
+		buf = append(buf, info.System[:]...)
+		buf = binary.BigEndian.AppendUint16(buf, info.Key)
+		buf = binary.BigEndian.AppendUint16(buf, info.PortPriority)
+		buf = binary.BigEndian.AppendUint16(buf, info.Port)
+		buf = append(buf, info.State, 0, 0, 0)
+	}
+	buf = append(buf, 3, LACP_COLL_LEN)
+	buf = binary.BigEndian.AppendUint16(buf, lacp.CollectorMaxDelay)
+	buf = append(buf, make([]byte, LACP_COLL_LEN-4)...)
+
+	// terminator and reserved bytes
+	for len(buf) < LACPDU_SIZE {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+func decodeLacp(data []byte) (*lacpHeader, error) {
+	if len(data) < 2+2*LACP_INFO_LEN {
+		return nil, fmt.Errorf("LACPDU too short: %d bytes", len(data))
+	}
+	if data[0] != LACP_SUBTYPE {
+		return nil, fmt.Errorf("Unsupported slow protocol subtype: %d", data[0])
+	}
+
+	lacp := &lacpHeader{Subtype: data[0], Version: data[1]}
+	for i, info := range []*lacpInfo{&lacp.Actor, &lacp.Partner} {
+		tlv := data[2+i*LACP_INFO_LEN:]
+		if tlv[0] != uint8(i+1) || tlv[1] != LACP_INFO_LEN {
+			return nil, fmt.Errorf("Malformed LACPDU")
+		}
+		info.SysPriority = binary.BigEndian.Uint16(tlv[2:])
+		copy(info.System[:], tlv[4:10])
+		info.Key = binary.BigEndian.Uint16(tlv[10:])
+		info.PortPriority = binary.BigEndian.Uint16(tlv[12:])
+		info.Port = binary.BigEndian.Uint16(tlv[14:])
+		info.State = tlv[16]
+	}
+	if rest := data[2+2*LACP_INFO_LEN:]; len(rest) >= 4 && rest[0] == 3 {
+		lacp.CollectorMaxDelay = binary.BigEndian.Uint16(rest[2:])
+	}
+	return lacp, nil
+}
+
+func appendLldpTlv(buf []byte, kind uint8, value []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(kind)<<9|uint16(len(value)))
+	return append(buf, value...)
+}
+
+// encodeLldp follows 802.1AB for a LLDPDU: chassis id, port id, ttl, system name, management
+// addr and end tlvs
+func encodeLldp(lldp *lldpHeader) []byte {
+	buf := appendLldpTlv(nil, LLDP_TLV_CHASSIS, append([]byte{LLDP_CHASSIS_MAC}, lldp.ChassisId[:]...))
+	buf = appendLldpTlv(buf, LLDP_TLV_PORT, append([]byte{LLDP_PORT_NAME}, lldp.PortId...))
+	buf = appendLldpTlv(buf, LLDP_TLV_TTL, binary.BigEndian.AppendUint16(nil, lldp.Ttl))
+	buf = appendLldpTlv(buf, LLDP_TLV_SYS_NAME, []byte(lldp.SysName))
+	if lldp.MgmtAddr != nil {
+		// addr string length, family, addr, interface numbering, interface and an empty oid
+		value := append([]byte{1 + 4, LLDP_ADDR_IPV4}, lldp.MgmtAddr[:]...)
+		value = append(value, LLDP_IFNUM_IFINDEX)
+		value = binary.BigEndian.AppendUint32(value, lldp.IfIndex)
+		buf = appendLldpTlv(buf, LLDP_TLV_MGMT_ADDR, append(value, 0))
+	}
+	return appendLldpTlv(buf, LLDP_TLV_END, nil)
+}
+
+// decodeLldp requires the chassis id, port id and ttl tlvs in that order, the optional tlvs
+// that aren't understood are skipped
+func decodeLldp(data []byte) (*lldpHeader, error) {
+	lldp := &lldpHeader{}
+	for i := 0; ; i++ {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("LLDPDU without an end tlv")
+		}
+		kind := uint8(data[0] >> 1)
+		size := int(binary.BigEndian.Uint16(data) & 0x01ff)
+		if len(data) < 2+size {
+			return nil, fmt.Errorf("LLDPDU tlv %d truncated", kind)
+		}
+		value := data[2 : 2+size]
+		data = data[2+size:]
+
+		if i < 3 && kind != uint8(LLDP_TLV_CHASSIS+i) {
+			return nil, fmt.Errorf("Malformed LLDPDU")
+		}
+		switch kind {
+		case LLDP_TLV_END:
+			return lldp, nil
+		case LLDP_TLV_CHASSIS:
+			if size != 7 || value[0] != LLDP_CHASSIS_MAC {
+				return nil, fmt.Errorf("Unsupported LLDP chassis id")
+			}
+			copy(lldp.ChassisId[:], value[1:])
+		case LLDP_TLV_PORT:
+			if size < 2 {
+				return nil, fmt.Errorf("Malformed LLDP port id")
+			}
+			lldp.PortId = string(value[1:])
+		case LLDP_TLV_TTL:
+			if size != 2 {
+				return nil, fmt.Errorf("Malformed LLDP ttl")
+			}
+			lldp.Ttl = binary.BigEndian.Uint16(value)
+		case LLDP_TLV_SYS_NAME:
+			lldp.SysName = string(value)
+		case LLDP_TLV_MGMT_ADDR:
+			if size >= 6 && value[0] == 1+4 && value[1] == LLDP_ADDR_IPV4 && lldp.MgmtAddr == nil {
+				var addr [4]byte
+				copy(addr[:], value[2:6])
+				lldp.MgmtAddr = &addr
+			}
+		}
+	}
+}
+
+// encodeIp follows RFC 791, TotalLength and CheckSum are computed here
+func encodeIp(ip *ipHeader) []byte {
+	ip.IHL = IP_HDR_MIN_SIZE / 4
+	ip.TotalLength = uint16(IP_HDR_MIN_SIZE + len(ip.Payload))
+
+	flags := ip.FragOffset & IP_FRAG_OFF_MASK
+	if ip.UnusedFlag {
+		flags |= IP_FLAG_RESERVED
+	}
+	if ip.DfFlag {
+		flags |= IP_FLAG_DF
+	}
+	if ip.MoreFlag {
+		flags |= IP_FLAG_MF
+	}
+
+	buf := make([]byte, 0, ip.TotalLength)
+	buf = append(buf, ip.Version<<4|ip.IHL, ip.TOS)
+	buf = binary.BigEndian.AppendUint16(buf, ip.TotalLength)
+	buf = binary.BigEndian.AppendUint16(buf, ip.Identification)
+	buf = binary.BigEndian.AppendUint16(buf, flags)
+	buf = append(buf, ip.TTL, ip.Protocol)
+	buf = binary.BigEndian.AppendUint16(buf, 0)
+	buf = append(buf, ip.SrcIpAddr[:]...)
+	buf = append(buf, ip.DstIpAddr[:]...)
+
+	ip.CheckSum = inetChecksum(buf)
+	binary.BigEndian.PutUint16(buf[10:], ip.CheckSum)
+
+	return append(buf, ip.Payload...)
+}
+
+func decodeIp(data []byte) (*ipHeader, error) {
+	if len(data) < IP_HDR_MIN_SIZE {
+		return nil, fmt.Errorf("IP packet too short: %d bytes", len(data))
+	}
+
+	ip := &ipHeader{
+		Version:        data[0] >> 4,
+		IHL:            data[0] & 0x0f,
+		TOS:            data[1],
+		TotalLength:    binary.BigEndian.Uint16(data[2:]),
+		Identification: binary.BigEndian.Uint16(data[4:]),
+		TTL:            data[8],
+		Protocol:       data[9],
+		CheckSum:       binary.BigEndian.Uint16(data[10:]),
+	}
+	hdrLen := int(ip.IHL) * 4
+	if ip.Version != 4 || hdrLen < IP_HDR_MIN_SIZE || int(ip.TotalLength) < hdrLen || int(ip.TotalLength) > len(data) {
+		return nil, fmt.Errorf("Malformed IP header")
+	}
+	if inetChecksum(data[:hdrLen]) != 0 {
+		return nil, fmt.Errorf("IP header with bad checksum")
+	}
+
+	flags := binary.BigEndian.Uint16(data[6:])
+	ip.UnusedFlag = flags&IP_FLAG_RESERVED != 0
+	ip.DfFlag = flags&IP_FLAG_DF != 0
+	ip.MoreFlag = flags&IP_FLAG_MF != 0
+	ip.FragOffset = flags & IP_FRAG_OFF_MASK
+	copy(ip.SrcIpAddr[:], data[12:16])
+	copy(ip.DstIpAddr[:], data[16:20])
+
+	// anything past TotalLength is ethernet padding
+	ip.Payload = data[hdrLen:ip.TotalLength]
+
+	return ip, nil
+}
+
+// inetChecksum is the 16 bit one's complement of the one's complement sum (RFC 1071)
+func inetChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -57,6 +57,7 @@ var root Param
 var show Param
 var config Param
 var run Param
+var clear Param
 
 func GetLeaf(param *Param) *Leaf {
 	return param.kind.leaf
@@ -117,6 +118,9 @@ func InitLibcli() {
 
 	InitParam(&run, CMD, "run", nil, nil, INVALID, "", "run command")
 	LibcliRegisterParam(&root, &run)
+
+	InitParam(&clear, CMD, "clear", nil, nil, INVALID, "", "clear command")
+	LibcliRegisterParam(&root, &clear)
 }
 
 func GetRootHook() *Param {
@@ -131,3 +135,6 @@ func GetRunHook() *Param {
 func GetShowHook() *Param {
 	return &show
 }
+func GetClearHook() *Param {
+	return &clear
+}
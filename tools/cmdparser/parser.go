@@ -3,13 +3,17 @@ package cmdparser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const MAX_SOURCE_DEPTH int = 8
+
 var buff *SerBuff
+var sourceDepth int
 
 func FindMatchingParam(param *Param, name string) (*Param, error) {
 	idx := -1
@@ -90,7 +94,9 @@ func parser(tokens []string) error {
 	}
 
 	if parent.fn != nil {
-		parent.fn(parent, buff)
+		if !parent.fn(parent, buff) {
+			return fmt.Errorf("Command failed")
+		}
 	} else {
 		return fmt.Errorf("Incomplete Command")
 	}
@@ -98,17 +104,74 @@ func parser(tokens []string) error {
 	return nil
 }
 
+// ExecuteCommand parses and runs a single command line. It can be called from within
+// a callback, hence the serialized buffer of the caller is saved and restored.
+func ExecuteCommand(line string) error {
+	saved := buff
+	buff = nil
+	defer func() { buff = saved }()
+
+	return parser(strings.Fields(line))
+}
+
+// ExecuteScript runs every command of the given file, skipping blank lines and '#' comments.
+// On error it either stops or carries on with the next line, the first error is returned.
+func ExecuteScript(path string, continueOnError bool) error {
+	if sourceDepth >= MAX_SOURCE_DEPTH {
+		return fmt.Errorf("Scripts nested more than %d levels deep", MAX_SOURCE_DEPTH)
+	}
+	sourceDepth++
+	defer func() { sourceDepth-- }()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Can't open script: %s", path)
+	}
+	defer file.Close()
+
+	var first error
+	scanner := bufio.NewScanner(file)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fmt.Println("router> " + line)
+		if err := ExecuteCommand(line); err != nil {
+			err = fmt.Errorf("%s:%d: %v", path, num, err)
+			if !continueOnError {
+				return err
+			}
+			fmt.Println(err)
+			if first == nil {
+				first = err
+			}
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error while reading script: %s", path)
+	}
+
+	return first
+}
+
 func CommandParser() {
+	reader := bufio.NewReader(os.Stdin)
 	for true {
-		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("router> ")
-		str, _ := reader.ReadString('\n')
-		tokens := strings.Split(strings.TrimSpace(str), " ")
+		str, err := reader.ReadString('\n')
+		if err == io.EOF && str == "" {
+			fmt.Println()
+			return
+		}
 
-		if err := parser(tokens); err != nil {
-			fmt.Println(err)
+		if strings.TrimSpace(str) != "" {
+			if err := ExecuteCommand(str); err != nil {
+				fmt.Println(err)
+			}
 		}
-		buff = nil
 		time.Sleep(time.Millisecond * 50)
 	}
 }
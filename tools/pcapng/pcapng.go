@@ -0,0 +1,141 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Minimal pcapng writer, see draft-ietf-opsawg-pcapng. Blocks are written in little endian,
+// timestamps use the default microsecond resolution.
+
+const (
+	SHB_TYPE       = 0x0A0D0D0A
+	IDB_TYPE       = 0x00000001
+	EPB_TYPE       = 0x00000006
+	BYTE_ORDER     = 0x1A2B3C4D
+	LINKTYPE_ETH   = 1
+	OPT_END        = 0
+	OPT_IF_NAME    = 2
+	OPT_EPB_FLAGS  = 2
+	DIR_INBOUND    = 1
+	DIR_OUTBOUND   = 2
+	MAX_SNAPLEN    = 0 // no limit
+	BLOCK_OVERHEAD = 12
+)
+
+type Writer struct {
+	lock   sync.Mutex
+	file   *os.File
+	intfs  map[string]uint32
+	closed bool
+}
+
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create capture file: %s", path)
+	}
+
+	w := &Writer{file: file, intfs: map[string]uint32{}}
+	body := binary.LittleEndian.AppendUint32(nil, BYTE_ORDER)
+	body = binary.LittleEndian.AppendUint16(body, 1)          // major version
+	body = binary.LittleEndian.AppendUint16(body, 0)          // minor version
+	body = binary.LittleEndian.AppendUint64(body, ^uint64(0)) // section length unknown
+	if err := writeBlock(w, SHB_TYPE, body); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// interfaceId returns the id of the named interface, describing it in the file on first use
+func interfaceId(w *Writer, name string) (uint32, error) {
+	if id, ok := w.intfs[name]; ok {
+		return id, nil
+	}
+
+	body := binary.LittleEndian.AppendUint16(nil, LINKTYPE_ETH)
+	body = binary.LittleEndian.AppendUint16(body, 0) // reserved
+	body = binary.LittleEndian.AppendUint32(body, MAX_SNAPLEN)
+	body = appendOption(body, OPT_IF_NAME, []byte(name))
+	body = appendOption(body, OPT_END, nil)
+	if err := writeBlock(w, IDB_TYPE, body); err != nil {
+		return 0, err
+	}
+
+	id := uint32(len(w.intfs))
+	w.intfs[name] = id
+	return id, nil
+}
+
+// WriteFrame records an ethernet frame seen on the named interface
+func WriteFrame(w *Writer, intf string, ts time.Time, frame []byte, outbound bool) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return fmt.Errorf("Capture file is closed")
+	}
+
+	id, err := interfaceId(w, intf)
+	if err != nil {
+		return err
+	}
+
+	usec := uint64(ts.UnixMicro())
+	flags := uint32(DIR_INBOUND)
+	if outbound {
+		flags = DIR_OUTBOUND
+	}
+
+	body := binary.LittleEndian.AppendUint32(nil, id)
+	body = binary.LittleEndian.AppendUint32(body, uint32(usec>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(usec))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(frame))) // captured length
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(frame))) // original length
+	body = append(body, frame...)
+	body = pad(body)
+	body = appendOption(body, OPT_EPB_FLAGS, binary.LittleEndian.AppendUint32(nil, flags))
+	body = appendOption(body, OPT_END, nil)
+
+	return writeBlock(w, EPB_TYPE, body)
+}
+
+func Close(w *Writer) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.file.Close()
+}
+
+func writeBlock(w *Writer, blockType uint32, body []byte) error {
+	total := uint32(len(body) + BLOCK_OVERHEAD)
+	buf := binary.LittleEndian.AppendUint32(nil, blockType)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	buf = append(buf, body...)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+
+	if _, err := w.file.Write(buf); err != nil {
+		return fmt.Errorf("Can't write to capture file: %s", w.file.Name())
+	}
+	return nil
+}
+
+func appendOption(buf []byte, code uint16, value []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	return pad(append(buf, value...))
+}
+
+// pad aligns the buffer to 32 bits
+func pad(buf []byte) []byte {
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
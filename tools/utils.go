@@ -1,9 +1,7 @@
 package tools
 
 import (
-	"bytes"
 	"crypto/rand"
-	"encoding/gob"
 	"fmt"
 	"math"
 	"strconv"
@@ -21,7 +19,8 @@ func GetSubnetFromMask(mask uint8) [4]byte {
 		idx++
 	}
 	if idx < 4 && b > 0 {
-		ans[idx] = uint8(math.Pow(2, b) - 1)
+		// the remaining bits of the mask are the high order ones of the next byte
+		ans[idx] = uint8(math.Pow(2, 8) - math.Pow(2, 8-b))
 	}
 
 	return ans
@@ -47,6 +46,17 @@ func ConvertStrToIp(addr string) [4]byte {
 	return ans
 }
 
+func ConvertStrToMac(addr string) [6]byte {
+	bytes := strings.Split(addr, ":")
+	var ans [6]byte
+	for i, val := range bytes {
+		num, _ := strconv.ParseUint(val, 16, 8)
+		ans[i] = uint8(num)
+	}
+
+	return ans
+}
+
 func ConvertAddrToStr(addr []byte) string {
 	var ans string
 	if len(addr) == 4 {
@@ -60,25 +70,3 @@ func ConvertAddrToStr(addr []byte) string {
 	}
 	return ans[:len(ans)-1]
 }
-
-func StructToByte[T any](data T) ([]byte, error) {
-	var buf bytes.Buffer
-	encoder := gob.NewEncoder(&buf)
-	if err := encoder.Encode(data); err != nil {
-		return nil, fmt.Errorf("Can't convert struct to byte array")
-	}
-
-	return buf.Bytes(), nil
-}
-
-func ByteToStruct[T any](data []byte, tmp T) (*T, error) {
-	var ans T
-	buf := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buf)
-	if err := decoder.Decode(&ans); err != nil {
-		fmt.Println(err)
-		return nil, fmt.Errorf("Can't decode strcut from byte array")
-	}
-
-	return &ans, nil
-}